@@ -0,0 +1,289 @@
+package main
+
+import (
+	"strings"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+var blockHighlightColor sdl.Color
+
+type Block struct {
+	AnchorX int
+	AnchorY int
+
+	X int
+	Y int
+}
+
+func (block *Block) Bounds() (int, int, int, int) {
+	return min(block.AnchorX, block.X), min(block.AnchorY, block.Y), max(block.AnchorX, block.X), max(block.AnchorY, block.Y)
+}
+
+func insertText(editor *Editor, row *Element, x int, text []byte) {
+	i := charChildIndex(row, x)
+
+	for _, c := range(text) {
+		row.InsertChild(newCharElement(editor, c), i)
+		i++
+	}
+}
+
+func padRow(editor *Editor, row *Element, x int) {
+	length := rowLength(row)
+
+	if length < x {
+		insertText(editor, row, length, []byte(strings.Repeat(" ", x - length)))
+	}
+}
+
+func (editor *Editor) SetBlock(anchorX, anchorY, x, y int) {
+	rows := len(editor.TextArea.Children)
+
+	editor.lastEdit = EditNone
+
+	editor.Block = &Block{
+		max(anchorX, 0),
+		min(max(anchorY, 0), rows - 1),
+		max(x, 0),
+		min(max(y, 0), rows - 1),
+	}
+
+	editor.updateBlock()
+}
+
+func (editor *Editor) ClearBlock() {
+	editor.Block = nil
+
+	editor.updateBlock()
+}
+
+func (editor *Editor) updateBlock() {
+	for _, char := range(editor.highlighted) {
+		char.BackgroundColor = sdl.Color{}
+		char.Invalidate()
+	}
+
+	editor.highlighted = nil
+
+	if editor.Block == nil {
+		return
+	}
+
+	left, top, right, bottom := editor.Block.Bounds()
+
+	for _, cursor := range(editor.Cursors) {
+		cursor.CursorElement.Remove()
+	}
+
+	editor.Cursors = nil
+
+	for y := top; y <= bottom; y++ {
+		if y == editor.Block.Y {
+			continue
+		}
+
+		cursor := NewCursor(editor)
+		editor.Cursors = append(editor.Cursors, cursor)
+		placeCursor(editor, cursor, editor.Block.X, y)
+	}
+
+	primary := NewCursor(editor)
+	editor.Cursors = append(editor.Cursors, primary)
+	placeCursor(editor, primary, editor.Block.X, editor.Block.Y)
+
+	for y := top; y <= bottom; y++ {
+		chars := rowChars(editor.TextArea.Children[y])
+
+		for _, char := range(chars[min(left, len(chars)):min(right, len(chars))]) {
+			char.BackgroundColor = blockHighlightColor
+			char.Invalidate()
+			editor.highlighted = append(editor.highlighted, char)
+		}
+	}
+}
+
+func (editor *Editor) ExtendBlock(code sdl.Keycode) {
+	if editor.Block == nil {
+		primary := editor.PrimaryCursor()
+		syncCursor(editor, primary)
+
+		editor.Block = &Block{primary.X, primary.Y, primary.X, primary.Y}
+	}
+
+	x, y := editor.Block.X, editor.Block.Y
+
+	switch code {
+	case sdl.K_RIGHT:
+		x++
+	case sdl.K_LEFT:
+		x--
+	case sdl.K_UP:
+		y--
+	case sdl.K_DOWN:
+		y++
+	}
+
+	editor.SetBlock(editor.Block.AnchorX, editor.Block.AnchorY, x, y)
+}
+
+func (editor *Editor) blockText() string {
+	left, top, right, bottom := editor.Block.Bounds()
+
+	var lines []string
+
+	for y := top; y <= bottom; y++ {
+		text := lineText(editor.TextArea.Children[y])
+
+		lines = append(lines, string(text[min(left, len(text)):min(right, len(text))]))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func (editor *Editor) CopyBlock() {
+	if editor.Block == nil {
+		return
+	}
+
+	editor.blockClipboard = editor.blockText()
+
+	sdl.SetClipboardText(editor.blockClipboard)
+}
+
+func (editor *Editor) deleteBlock() {
+	left, top, right, bottom := editor.Block.Bounds()
+
+	for _, char := range(editor.highlighted) {
+		char.BackgroundColor = sdl.Color{}
+		char.Invalidate()
+	}
+
+	editor.highlighted = nil
+
+	for y := top; y <= bottom; y++ {
+		chars := rowChars(editor.TextArea.Children[y])
+
+		for _, char := range(chars[min(left, len(chars)):min(right, len(chars))]) {
+			char.Remove()
+		}
+	}
+
+	editor.Block.AnchorX = left
+	editor.Block.X = left
+}
+
+func (editor *Editor) CommitBlock() {
+	if editor.Block == nil {
+		return
+	}
+
+	left, top, _, bottom := editor.Block.Bounds()
+
+	for y := top; y <= bottom; y++ {
+		padRow(editor, editor.TextArea.Children[y], left)
+	}
+
+	editor.deleteBlock()
+	editor.updateBlock()
+
+	editor.Block = nil
+	editor.highlighted = nil
+}
+
+// IndentBlock indents, or with outdent removes one level of indentation from,
+// every line the block covers. The block moves with the text it covers.
+func (editor *Editor) IndentBlock(outdent bool) {
+	if editor.Block == nil {
+		return
+	}
+
+	block := *editor.Block
+
+	// The block follows the indentation of the line its cursor is on.
+	shift := 0
+
+	editor.ApplyLines(false, func(cursor *Cursor) {
+		n := indentWidth
+		if outdent {
+			n = -outdentLine(editor, cursor)
+		} else {
+			indentLine(editor, cursor)
+		}
+
+		if cursor.Y == block.Y {
+			shift = n
+		}
+	})
+
+	editor.SetBlock(max(block.AnchorX + shift, 0), block.AnchorY, max(block.X + shift, 0), block.Y)
+}
+
+// JoinBlockLines joins the lines the block covers into one, leaving the cursor
+// where the last two were joined.
+func (editor *Editor) JoinBlockLines() {
+	if editor.Block == nil {
+		return
+	}
+
+	_, top, _, bottom := editor.Block.Bounds()
+
+	editor.SetCursor(0, top)
+
+	for y := top; y < bottom; y++ {
+		joinLines(editor, editor.PrimaryCursor())
+	}
+
+	editor.syncCursors()
+}
+
+func (editor *Editor) CutBlock() {
+	if editor.Block == nil {
+		return
+	}
+
+	editor.CopyBlock()
+	editor.deleteBlock()
+	editor.updateBlock()
+}
+
+func (editor *Editor) Paste() {
+	text, err := sdl.GetClipboardText()
+	if err != nil || text == "" {
+		return
+	}
+
+	text = strings.ReplaceAll(text, "\r", "")
+
+	editor.CommitBlock()
+
+	if text == editor.blockClipboard {
+		editor.pasteBlock(strings.Split(text, "\n"))
+		return
+	}
+
+	editor.Apply(func(cursor *Cursor) {
+		for _, c := range([]byte(text)) {
+			writeChar(editor, cursor, c)
+		}
+	})
+}
+
+func (editor *Editor) pasteBlock(lines []string) {
+	primary := editor.PrimaryCursor()
+	syncCursor(editor, primary)
+
+	x, y := primary.X, primary.Y
+
+	for i, line := range(lines) {
+		for y + i >= len(editor.TextArea.Children) {
+			editor.TextArea.AppendChild(newLineElement(editor))
+		}
+
+		row := editor.TextArea.Children[y + i]
+
+		padRow(editor, row, x)
+		insertText(editor, row, x, []byte(line))
+	}
+
+	editor.SetCursor(x + len(lines[len(lines) - 1]), y + len(lines) - 1)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"time"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+const (
+	CursorBar = iota
+	CursorBlock
+	CursorUnderline
+)
+
+var cursorColor sdl.Color
+
+func parseCursorStyle(name string) int {
+	switch name {
+	case "block":
+		return CursorBlock
+	case "underline":
+		return CursorUnderline
+	}
+
+	return CursorBar
+}
+
+func (editor *Editor) cursorSize() (int32, int32) {
+	if editor.Font == nil {
+		return 5, 40
+	}
+
+	return charWidth(editor.Font), fontHeight(editor.Font)
+}
+
+func (editor *Editor) sizeCursor(cursor *Cursor) {
+	cursor.CursorElement.Width, cursor.CursorElement.Height = editor.cursorSize()
+}
+
+func (editor *Editor) paintCursor(renderer *sdl.Renderer, dimensions *sdl.Rect) {
+	if !editor.cursorsVisible && editor.Focused {
+		return
+	}
+
+	// Only the selected one of several text areas shows its cursors.
+	if editor.TextArea.Selectable && !editor.TextArea.Selected {
+		return
+	}
+
+	renderer.SetDrawColor(cursorColor.R, cursorColor.G, cursorColor.B, cursorColor.A)
+
+	if !editor.Focused {
+		drawThickRect(renderer, dimensions, 1)
+		return
+	}
+
+	switch editor.CursorStyle {
+	case CursorBlock:
+		renderer.SetDrawColor(cursorColor.R, cursorColor.G, cursorColor.B, 128)
+		renderer.FillRect(dimensions)
+	case CursorUnderline:
+		renderer.FillRect(&sdl.Rect{dimensions.X, dimensions.Y + dimensions.H - 3, dimensions.W, 3})
+	default:
+		renderer.FillRect(&sdl.Rect{dimensions.X, dimensions.Y, 2, dimensions.H})
+	}
+}
+
+func (editor *Editor) invalidateCursors() {
+	for _, cursor := range(editor.Cursors) {
+		cursor.CursorElement.Invalidate()
+	}
+}
+
+func (editor *Editor) SetBlinkRate(rate time.Duration) {
+	editor.BlinkRate = rate
+
+	if editor.blinkTimer != nil {
+		editor.blinkTimer.Stop()
+		editor.blinkTimer = nil
+	}
+
+	editor.cursorsVisible = true
+
+	if rate > 0 {
+		editor.blinkTimer = AddTimer(rate, true, func() {
+			if !editor.Focused {
+				return
+			}
+
+			editor.cursorsVisible = !editor.cursorsVisible
+			editor.invalidateCursors()
+		})
+	}
+
+	editor.invalidateCursors()
+}
+
+func (editor *Editor) ResetBlink() {
+	if !editor.cursorsVisible {
+		editor.cursorsVisible = true
+		editor.invalidateCursors()
+	}
+
+	if editor.blinkTimer != nil {
+		editor.blinkTimer.Reset()
+	}
+}
+
+func (editor *Editor) SetFocused(focused bool) {
+	editor.Focused = focused
+
+	editor.ResetBlink()
+	editor.invalidateCursors()
+}
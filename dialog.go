@@ -0,0 +1,211 @@
+package main
+
+import (
+	"strings"
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/veandco/go-sdl2/ttf"
+)
+
+var dialogColor sdl.Color
+
+type DialogResult struct {
+	// Button is the index of the button that closed the dialog.
+	Button int
+
+	// Text is the content of the text field, if the dialog has one.
+	Text string
+}
+
+type DialogOptions struct {
+	Title string
+	Message string
+	Buttons []string
+
+	// DefaultButton is pressed by Enter and CancelButton by Escape.
+	DefaultButton int
+	CancelButton int
+
+	// Prompt adds a text field holding Text.
+	Prompt bool
+	Text string
+
+	OnClose func(DialogResult)
+}
+
+// Dialog is a modal popup on the root. While it is open it receives all
+// keyboard input and nothing below it can be clicked.
+type Dialog struct {
+	Element *Element
+
+	Options DialogOptions
+
+	Field *TextField
+
+	Buttons []*Button
+
+	Result chan DialogResult
+
+	root *Element
+	font *ttf.Font
+}
+
+func ShowDialog(root *Element, font *ttf.Font, options DialogOptions) *Dialog {
+	if len(options.Buttons) == 0 {
+		options.Buttons = []string{"OK"}
+	}
+
+	dialog := &Dialog{
+		Options: options,
+		Result: make(chan DialogResult, 1),
+		root: root,
+		font: font,
+	}
+
+	dialog.Element = &Element{
+		Width: -1,
+		Height: -1,
+		MinWidth: 360,
+
+		Layout: LayoutFlex,
+		Direction: DirectionColumn,
+		Gap: 12,
+
+		PaddingTop: 16,
+		PaddingRight: 16,
+		PaddingBottom: 16,
+		PaddingLeft: 16,
+
+		BorderWidth: 1,
+		CornerRadius: 8,
+
+		ShadowColor: sdl.Color{0, 0, 0, 128},
+		ShadowOffsetY: 4,
+		ShadowBlur: 8,
+
+		IsTextInput: options.Prompt,
+
+		// The dialog takes focus itself and hands keys to its text field.
+		Selectable: true,
+		HideFocusRing: true,
+
+		Modal: true,
+		Centered: true,
+		ZIndex: 100,
+	}
+
+	if options.Title != "" {
+		dialog.Element.AppendChild(NewLabel(font, options.Title).Element)
+	}
+
+	for _, line := range(strings.Split(options.Message, "\n")) {
+		if line == "" {
+			continue
+		}
+
+		dialog.Element.AppendChild(NewLabel(font, line).Element)
+	}
+
+	if options.Prompt {
+		dialog.Field = NewTextField(font, options.Text)
+
+		// The field gets its keys through the dialog, so it is never the
+		// selected element and always shows its cursor.
+		dialog.Field.Element.Width = -1
+		dialog.Field.Element.Selectable = false
+
+		dialog.Element.AppendChild(dialog.Field.Element)
+	}
+
+	buttonRow := &Element{
+		Width: -1,
+		Height: -1,
+
+		Layout: LayoutFlex,
+		Justify: JustifyEnd,
+		Gap: 8,
+	}
+
+	for i, label := range(options.Buttons) {
+		button := NewButton(font, label, func() {
+			dialog.Press(i)
+		})
+
+		if i == options.DefaultButton {
+			button.Element.OnTheme(func() {
+				button.Color = widgetAccentColor
+				button.updateColor()
+			})
+		}
+
+		dialog.Buttons = append(dialog.Buttons, button)
+
+		buttonRow.AppendChild(button.Element)
+	}
+
+	dialog.Element.AppendChild(buttonRow)
+
+	dialog.Element.AddEventHandler(dialog.handleEvent)
+
+	dialog.Element.OnTheme(func() {
+		dialog.Element.BackgroundColor = dialogColor
+		dialog.Element.BorderColor = widgetBorderColor
+	})
+
+	root.OpenPopup(dialog.Element)
+
+	return dialog
+}
+
+// Text returns the content of the text field.
+func (dialog *Dialog) Text() string {
+	if dialog.Field == nil {
+		return ""
+	}
+
+	return dialog.Field.Text()
+}
+
+// Press closes the dialog as if button i had been clicked.
+func (dialog *Dialog) Press(i int) {
+	if dialog.Element.Parent == nil {
+		return
+	}
+
+	dialog.root.ClosePopup(dialog.Element)
+
+	result := DialogResult{i, dialog.Text()}
+
+	dialog.Result <- result
+
+	if dialog.Options.OnClose != nil {
+		dialog.Options.OnClose(result)
+	}
+}
+
+func (dialog *Dialog) handleEvent(event Event) {
+	if e, ok := event.(*KeyEvent); ok && e.Type == sdl.KEYDOWN {
+		switch e.Code {
+		case sdl.K_RETURN, sdl.K_KP_ENTER:
+			dialog.Press(dialog.Options.DefaultButton)
+			return
+		case sdl.K_ESCAPE:
+			dialog.Press(dialog.Options.CancelButton)
+			return
+		}
+	}
+
+	// Keys only go to the text field while the dialog itself has focus, not
+	// one of its buttons.
+	if dialog.Field != nil && event.base().Target == dialog.Element {
+		dialog.Field.HandleEvent(event)
+	}
+}
+
+// ShowMessage shows a dialog with a single button.
+func ShowMessage(root *Element, font *ttf.Font, title, message, button string) *Dialog {
+	return ShowDialog(root, font, DialogOptions{
+		Title: title,
+		Message: message,
+		Buttons: []string{button},
+	})
+}
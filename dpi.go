@@ -0,0 +1,144 @@
+package main
+
+import (
+	"math"
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/veandco/go-sdl2/ttf"
+)
+
+// uiScale is the number of output pixels per logical unit. Element sizes and
+// positions are all in logical units, and each element texture is painted at
+// the pixel size with the renderer scaled, so text rasterized at the pixel
+// size stays sharp.
+var uiScale = 1.0
+
+// pointScale is the number of output pixels per window coordinate, which is
+// what mouse positions are in. It is above 1 on displays where the system
+// already scales windows, like macOS with a Retina display.
+var pointScale = 1.0
+
+// scaleDisplay is the display uiScale was last detected for.
+var scaleDisplay = -1
+
+// toPixels converts a logical size to output pixels.
+func toPixels(size int32) int32 {
+	return int32(math.Round(float64(size) * uiScale))
+}
+
+// toLogical converts a size in output pixels to logical units, rounding up so
+// that content measured in pixels is never clipped.
+func toLogical(size int32) int32 {
+	return int32(math.Ceil(float64(size) / uiScale - 0.001))
+}
+
+// windowToLogical converts a position in window coordinates to logical units.
+func windowToLogical(x, y int32) (int32, int32) {
+	factor := pointScale / uiScale
+
+	return int32(math.Floor(float64(x) * factor)), int32(math.Floor(float64(y) * factor))
+}
+
+// logicalToWindow converts a rectangle in logical units to window
+// coordinates.
+func logicalToWindow(rect sdl.Rect) sdl.Rect {
+	factor := uiScale / pointScale
+
+	scale := func(v int32) int32 {
+		return int32(math.Round(float64(v) * factor))
+	}
+
+	return sdl.Rect{scale(rect.X), scale(rect.Y), scale(rect.W), scale(rect.H)}
+}
+
+func updatePointScale(window *sdl.Window, renderer *sdl.Renderer) {
+	windowW, _ := window.GetSize()
+
+	outputW, _, err := renderer.GetOutputSize()
+	if err != nil || windowW <= 0 {
+		return
+	}
+
+	pointScale = float64(outputW) / float64(windowW)
+}
+
+// detectScale sets uiScale for the display the window is on, unless scale
+// gives one. A window the system already scales uses its pixel density, and
+// otherwise the DPI of the display is compared to the usual 96, in steps of
+// a quarter.
+func detectScale(window *sdl.Window, renderer *sdl.Renderer, scale float64) {
+	updatePointScale(window, renderer)
+
+	scaleDisplay, _ = window.GetDisplayIndex()
+
+	switch {
+	case scale > 0:
+		uiScale = scale
+	case pointScale > 1:
+		uiScale = pointScale
+	default:
+		uiScale = 1
+
+		display, err := window.GetDisplayIndex()
+		if err != nil {
+			return
+		}
+
+		dpi, _, _, err := sdl.GetDisplayDPI(display)
+		if err != nil || dpi <= 0 {
+			return
+		}
+
+		uiScale = max(math.Round(float64(dpi) / 96 * 4) / 4, 1)
+	}
+}
+
+// updateScale detects the scale again if the window has moved to another
+// display or the system has changed how it scales the window, and reports
+// whether uiScale changed.
+func updateScale(window *sdl.Window, renderer *sdl.Renderer, scale float64) bool {
+	oldPointScale, oldScale := pointScale, uiScale
+
+	updatePointScale(window, renderer)
+
+	display, err := window.GetDisplayIndex()
+	if err != nil {
+		display = scaleDisplay
+	}
+
+	if pointScale == oldPointScale && display == scaleDisplay {
+		return false
+	}
+
+	detectScale(window, renderer, scale)
+
+	return uiScale != oldScale
+}
+
+// reopenFont opens the font at path again at a new size in pixels. The font
+// is replaced in place, because every text element holds a pointer to it.
+func reopenFont(font *ttf.Font, path string, size int) error {
+	reopened, err := ttf.OpenFont(path, size)
+	if err != nil {
+		return err
+	}
+
+	old := *font
+	*font = *reopened
+
+	old.Close()
+
+	return nil
+}
+
+// fontHeight returns the height of a line of font in logical units.
+func fontHeight(font *ttf.Font) int32 {
+	return toLogical(int32(font.Height()))
+}
+
+// charWidth returns the width of a character of the monospaced font in
+// logical units.
+func charWidth(font *ttf.Font) int32 {
+	width, _ := measureText(font, "M")
+
+	return width
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+)
+
+func isWordChar(c byte) bool {
+	return c == '_' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func isSpaceChar(c byte) bool {
+	return c == ' ' || c == '\t'
+}
+
+func charOf(element *Element) byte {
+	return element.Content.(*Text).Content[0]
+}
+
+func lineText(row *Element) []byte {
+	var text []byte
+
+	for _, c := range(row.Children) {
+		if c.Content == nil {
+			continue
+		}
+
+		text = append(text, c.Content.(*Text).Content...)
+	}
+
+	return text
+}
+
+func wordBoundaryLeft(text []byte, x int) int {
+	for x > 0 && isSpaceChar(text[x - 1]) {
+		x--
+	}
+
+	if x > 0 && isWordChar(text[x - 1]) {
+		for x > 0 && isWordChar(text[x - 1]) {
+			x--
+		}
+	} else {
+		for x > 0 && !isWordChar(text[x - 1]) && !isSpaceChar(text[x - 1]) {
+			x--
+		}
+	}
+
+	return x
+}
+
+func wordBoundaryRight(text []byte, x int) int {
+	for x < len(text) && isSpaceChar(text[x]) {
+		x++
+	}
+
+	if x < len(text) && isWordChar(text[x]) {
+		for x < len(text) && isWordChar(text[x]) {
+			x++
+		}
+	} else {
+		for x < len(text) && !isWordChar(text[x]) && !isSpaceChar(text[x]) {
+			x++
+		}
+	}
+
+	return x
+}
+
+// wordAt returns the bounds of the run of word characters, spaces or
+// punctuation around x. A word on either side of x is preferred, then the
+// character after x.
+func wordAt(text []byte, x int) (int, int) {
+	x = min(max(x, 0), len(text))
+
+	if len(text) == 0 {
+		return 0, 0
+	}
+
+	i := x
+	if i == len(text) || (i > 0 && !isWordChar(text[i]) && isWordChar(text[i - 1])) {
+		i--
+	}
+
+	class := charClass(text[i])
+
+	start, end := i, i + 1
+
+	for start > 0 && charClass(text[start - 1]) == class {
+		start--
+	}
+
+	for end < len(text) && charClass(text[end]) == class {
+		end++
+	}
+
+	return start, end
+}
+
+func charClass(c byte) int {
+	switch {
+	case isSpaceChar(c):
+		return 0
+	case isWordChar(c):
+		return 1
+	}
+
+	return 2
+}
+
+func copyLine(editor *Editor, text []byte) *Element {
+	line := newLineElement(editor)
+
+	for _, c := range(text) {
+		line.AppendChild(newCharElement(editor, c))
+	}
+
+	return line
+}
+
+func deleteWordLeft(editor *Editor, cursor *Cursor) {
+	if cursor.X == 0 {
+		writeChar(editor, cursor, '\b')
+		return
+	}
+
+	row := cursor.CursorElement.Parent
+
+	chars := rowChars(row)
+
+	start := wordBoundaryLeft(lineText(row), cursor.X)
+
+	for _, char := range(chars[start:cursor.X]) {
+		char.Remove()
+	}
+}
+
+func deleteWordRight(editor *Editor, cursor *Cursor) {
+	row := cursor.CursorElement.Parent
+
+	chars := rowChars(row)
+
+	if cursor.X == len(chars) {
+		writeChar(editor, cursor, '\x7F')
+		return
+	}
+
+	end := wordBoundaryRight(lineText(row), cursor.X)
+
+	for _, char := range(chars[cursor.X:end]) {
+		char.Remove()
+	}
+}
+
+// indentWidth is how many columns Tab indents by. Indentation is made of
+// spaces, which the font draws, unlike tab characters.
+const indentWidth = 4
+
+// indent writes spaces up to the next indentation column.
+func indent(editor *Editor, cursor *Cursor) {
+	for i := cursor.X % indentWidth; i < indentWidth; i++ {
+		writeChar(editor, cursor, ' ')
+	}
+}
+
+// indentLine adds one level of indentation to the start of the line the
+// cursor is on.
+func indentLine(editor *Editor, cursor *Cursor) {
+	insertText(editor, cursor.CursorElement.Parent, 0, []byte(strings.Repeat(" ", indentWidth)))
+
+	placeCursor(editor, cursor, cursor.X + indentWidth, cursor.Y)
+}
+
+// outdentLine removes up to one level of indentation from the start of the
+// line the cursor is on, and returns how many columns it removed.
+func outdentLine(editor *Editor, cursor *Cursor) int {
+	chars := rowChars(cursor.CursorElement.Parent)
+
+	n := 0
+	for n < len(chars) && n < indentWidth && charOf(chars[n]) == ' ' {
+		n++
+	}
+
+	for _, char := range(chars[:n]) {
+		char.Remove()
+	}
+
+	placeCursor(editor, cursor, max(cursor.X - n, 0), cursor.Y)
+
+	return n
+}
+
+func deleteLine(editor *Editor, cursor *Cursor) {
+	row := cursor.CursorElement.Parent
+	y := cursor.Y
+
+	if len(editor.TextArea.Children) == 1 {
+		for _, char := range(rowChars(row)) {
+			char.Remove()
+		}
+	} else {
+		row.Remove()
+	}
+
+	for _, other := range(editor.Cursors) {
+		if other.CursorElement.Parent == row {
+			placeCursor(editor, other, other.X, y)
+		}
+	}
+}
+
+func duplicateLine(editor *Editor, cursor *Cursor) {
+	line := copyLine(editor, lineText(cursor.CursorElement.Parent))
+
+	editor.TextArea.InsertChild(line, cursor.Y + 1)
+
+	placeCursor(editor, cursor, cursor.X, cursor.Y + 1)
+}
+
+func joinLines(editor *Editor, cursor *Cursor) {
+	if cursor.Y + 1 >= len(editor.TextArea.Children) {
+		return
+	}
+
+	row := editor.TextArea.Children[cursor.Y]
+	nextRow := editor.TextArea.Children[cursor.Y + 1]
+
+	nextChars := rowChars(nextRow)
+	for len(nextChars) > 0 && isSpaceChar(charOf(nextChars[0])) {
+		nextChars[0].Remove()
+		nextChars = nextChars[1:]
+	}
+
+	chars := rowChars(row)
+	for len(chars) > 0 && isSpaceChar(charOf(chars[len(chars) - 1])) {
+		chars[len(chars) - 1].Remove()
+		chars = chars[:len(chars) - 1]
+	}
+
+	joinX := len(chars)
+
+	if len(chars) > 0 && len(nextChars) > 0 {
+		row.AppendChild(newCharElement(editor, ' '))
+	}
+
+	for len(nextRow.Children) > 0 {
+		child := nextRow.Children[0]
+		child.Remove()
+		row.AppendChild(child)
+	}
+
+	nextRow.Remove()
+
+	placeCursor(editor, cursor, joinX, cursor.Y)
+}
+
+func sortLines(editor *Editor, from, to int, reverse bool) {
+	rows := editor.TextArea.Children[from:to]
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if reverse {
+			return bytes.Compare(lineText(rows[i]), lineText(rows[j])) > 0
+		}
+
+		return bytes.Compare(lineText(rows[i]), lineText(rows[j])) < 0
+	})
+
+	editor.syncCursors()
+}
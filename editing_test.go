@@ -0,0 +1,157 @@
+package main
+
+import (
+	"testing"
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/veandco/go-sdl2/ttf"
+)
+
+// testEditor returns an editor holding text, or skips the test if the font
+// cannot be opened.
+func testEditor(t *testing.T, text string) *Editor {
+	err := ttf.Init()
+	if err != nil {
+		t.Skip(err)
+	}
+
+	font, err := ttf.OpenFont(fontPath, fontSize)
+	if err != nil {
+		t.Skip(err)
+	}
+
+	t.Cleanup(font.Close)
+
+	editor := NewEditor(&Element{Width: 400, Height: 400}, font)
+	editor.SetText([]byte(text))
+
+	return editor
+}
+
+func pressKey(editor *Editor, code sdl.Keycode, mod uint16) {
+	editor.HandleEvent(&KeyEvent{Type: sdl.KEYDOWN, Code: code, Mod: mod})
+}
+
+func TestTabIndentsSelection(t *testing.T) {
+	tests := []struct {
+		name string
+		mod uint16
+		text string
+		want string
+	}{
+		{"indent", 0, "hello world\nsecond\nthird", "    hello world\n    second\nthird"},
+		{"outdent", sdl.KMOD_LSHIFT, "      hello world\n  second\nthird", "  hello world\nsecond\nthird"},
+	}
+
+	for _, test := range(tests) {
+		editor := testEditor(t, test.text)
+		editor.SetBlock(2, 0, 8, 1)
+
+		pressKey(editor, sdl.K_TAB, test.mod)
+
+		if text := editor.Text(); text != test.want {
+			t.Errorf("%s: text %q, want %q", test.name, text, test.want)
+		}
+
+		if editor.Block == nil {
+			t.Errorf("%s: the selection is gone", test.name)
+		}
+	}
+
+	editor := testEditor(t, "hello world\nsecond")
+	editor.SelectLine(0)
+
+	pressKey(editor, sdl.K_TAB, 0)
+
+	if editor.Block == nil {
+		t.Errorf("the selection is gone after indenting a line")
+	} else if text := editor.blockText(); text != "hello world" {
+		t.Errorf("selection after indenting a line covers %q, want %q", text, "hello world")
+	}
+}
+
+func TestMoveLinesTogether(t *testing.T) {
+	tests := []struct {
+		name string
+		lines []int
+		code sdl.Keycode
+		want string
+	}{
+		{"down", []int{0, 1}, sdl.K_DOWN, "c\na\nb"},
+		{"up", []int{1, 2}, sdl.K_UP, "b\nc\na"},
+		{"bottom edge", []int{1, 2}, sdl.K_DOWN, "a\nb\nc"},
+		{"top edge", []int{0, 1}, sdl.K_UP, "a\nb\nc"},
+	}
+
+	for _, test := range(tests) {
+		editor := testEditor(t, "a\nb\nc")
+		editor.SetCursor(0, test.lines[0])
+		editor.AddCursor(0, test.lines[1])
+
+		pressKey(editor, test.code, sdl.KMOD_LALT)
+
+		if text := editor.Text(); text != test.want {
+			t.Errorf("%s: text %q, want %q", test.name, text, test.want)
+		}
+	}
+}
+
+func TestUndoLineCommands(t *testing.T) {
+	tests := []struct {
+		name string
+		code sdl.Keycode
+		mod uint16
+		want string
+	}{
+		{"sort", sdl.K_F9, 0, "b\nc\na"},
+		{"join", sdl.K_j, sdl.KMOD_LCTRL, "c b a"},
+		{"move", sdl.K_DOWN, sdl.KMOD_LALT, "a\nc\nb"},
+		{"delete", sdl.K_k, sdl.KMOD_LCTRL | sdl.KMOD_LSHIFT, "a"},
+	}
+
+	for _, test := range(tests) {
+		editor := testEditor(t, "c\nb\na")
+		editor.SetCursor(0, 0)
+		editor.AddCursor(0, 1)
+
+		pressKey(editor, test.code, test.mod)
+
+		if text := editor.Text(); text != test.want {
+			t.Errorf("%s: text %q, want %q", test.name, text, test.want)
+		}
+
+		pressKey(editor, sdl.K_z, sdl.KMOD_LCTRL)
+
+		if text := editor.Text(); text != "c\nb\na" {
+			t.Errorf("%s: text after undo %q, want %q", test.name, text, "c\nb\na")
+		}
+
+		pressKey(editor, sdl.K_y, sdl.KMOD_LCTRL)
+
+		if text := editor.Text(); text != test.want {
+			t.Errorf("%s: text after redo %q, want %q", test.name, text, test.want)
+		}
+	}
+}
+
+func TestUndoTyping(t *testing.T) {
+	editor := testEditor(t, "")
+
+	for _, c := range([]byte("ab")) {
+		editor.HandleEvent(&TextEvent{Char: c})
+	}
+
+	pressKey(editor, sdl.K_LEFT, 0)
+	editor.HandleEvent(&TextEvent{Char: 'c'})
+
+	pressKey(editor, sdl.K_z, sdl.KMOD_LCTRL)
+
+	if text := editor.Text(); text != "ab" {
+		t.Errorf("text after one undo %q, want %q", text, "ab")
+	}
+
+	pressKey(editor, sdl.K_z, sdl.KMOD_LCTRL)
+
+	if text := editor.Text(); text != "" {
+		t.Errorf("text after two undos %q, want %q", text, "")
+	}
+}
@@ -0,0 +1,937 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"time"
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/veandco/go-sdl2/ttf"
+)
+
+type Editor struct {
+	TextArea *Element
+	Font *ttf.Font
+
+	Cursors []*Cursor
+
+	Block *Block
+	highlighted []*Element
+	blockClipboard string
+
+	SoftWrap bool
+	WrapColumn int
+
+	ScrollMarginLines int
+	ScrollMarginColumns int
+	revealPending bool
+
+	CursorStyle int
+	BlinkRate time.Duration
+	blinkTimer *Timer
+	cursorsVisible bool
+	Focused bool
+
+	Dragging bool
+	DragAnchorX int
+	DragAnchorY int
+
+	undoStack []*undoState
+	redoStack []*undoState
+	lastEdit int
+}
+
+func NewEditor(textArea *Element, font *ttf.Font) *Editor {
+	editor := &Editor{
+		TextArea: textArea,
+		Font: font,
+
+		SoftWrap: true,
+
+		ScrollMarginLines: 2,
+		ScrollMarginColumns: 4,
+
+		cursorsVisible: true,
+		Focused: true,
+	}
+
+	textArea.AppendChild(newLineElement(editor))
+
+	textArea.AddEventHandler(editor.HandlePointer)
+
+	textArea.OnTheme(editor.restyle)
+
+	textArea.editor = editor
+
+	cursor := NewCursor(editor)
+	editor.Cursors = []*Cursor{cursor}
+	placeCursor(editor, cursor, 0, 0)
+
+	return editor
+}
+
+// restyle gives the text and selection the colors of the current theme.
+func (editor *Editor) restyle() {
+	for _, row := range(editor.TextArea.Children) {
+		for _, char := range(rowChars(row)) {
+			char.Content.(*Text).Color = editorTextColor
+			char.Invalidate()
+		}
+	}
+
+	for _, char := range(editor.highlighted) {
+		char.BackgroundColor = blockHighlightColor
+	}
+
+	editor.invalidateCursors()
+}
+
+// resize fits the cursors and rows to the font after the scale has changed.
+func (editor *Editor) resize() {
+	for _, cursor := range(editor.Cursors) {
+		editor.sizeCursor(cursor)
+	}
+
+	for _, row := range(editor.TextArea.Children) {
+		editor.styleLine(row)
+	}
+}
+
+func (editor *Editor) PrimaryCursor() *Cursor {
+	return editor.Cursors[len(editor.Cursors) - 1]
+}
+
+// Text returns the contents of the editor, with the rows joined by newlines.
+func (editor *Editor) Text() string {
+	var lines []string
+
+	for _, row := range(editor.TextArea.Children) {
+		lines = append(lines, string(lineText(row)))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// SetText replaces the contents of the editor, leaving a single cursor at the
+// start.
+func (editor *Editor) SetText(text []byte) {
+	editor.SetCursor(0, 0)
+
+	rows := editor.TextArea.Children
+
+	for i := len(rows) - 1; i > 0; i-- {
+		rows[i].Remove()
+	}
+
+	chars := rowChars(editor.TextArea.Children[0])
+
+	for i := len(chars) - 1; i >= 0; i-- {
+		chars[i].Remove()
+	}
+
+	for _, c := range(text) {
+		writeChar(editor, editor.PrimaryCursor(), c)
+	}
+
+	editor.SetCursor(0, 0)
+	editor.revealPending = true
+}
+
+func rowChars(row *Element) []*Element {
+	var chars []*Element
+
+	for _, child := range(row.Children) {
+		if child.Content != nil {
+			chars = append(chars, child)
+		}
+	}
+
+	return chars
+}
+
+func rowLength(row *Element) int {
+	length := 0
+
+	for _, child := range(row.Children) {
+		if child.Content != nil {
+			length++
+		}
+	}
+
+	return length
+}
+
+func charChildIndex(row *Element, x int) int {
+	for i, child := range(row.Children) {
+		if child.Content == nil {
+			continue
+		}
+
+		if x == 0 {
+			return i
+		}
+
+		x--
+	}
+
+	return len(row.Children)
+}
+
+func charIndex(row *Element, element *Element) int {
+	x := 0
+
+	for _, child := range(row.Children) {
+		if child == element {
+			break
+		}
+
+		if child.Content != nil {
+			x++
+		}
+	}
+
+	return x
+}
+
+func syncCursor(editor *Editor, cursor *Cursor) {
+	row := cursor.CursorElement.Parent
+
+	if cursor.Y >= len(editor.TextArea.Children) || editor.TextArea.Children[cursor.Y] != row {
+		cursor.Y = row.Index()
+	}
+
+	cursor.X = charIndex(row, cursor.CursorElement)
+}
+
+func placeCursor(editor *Editor, cursor *Cursor, x, y int) {
+	cursor.CursorElement.Remove()
+
+	cursor.Y = min(max(y, 0), len(editor.TextArea.Children) - 1)
+
+	row := editor.TextArea.Children[cursor.Y]
+
+	cursor.X = min(max(x, 0), rowLength(row))
+
+	row.InsertChild(cursor.CursorElement, charChildIndex(row, cursor.X))
+}
+
+func (editor *Editor) SetCursor(x, y int) {
+	editor.ClearBlock()
+
+	primary := editor.PrimaryCursor()
+
+	for _, cursor := range(editor.Cursors) {
+		if cursor != primary {
+			cursor.CursorElement.Remove()
+		}
+	}
+
+	editor.Cursors = []*Cursor{primary}
+	editor.lastEdit = EditNone
+
+	placeCursor(editor, primary, x, y)
+}
+
+func (editor *Editor) AddCursor(x, y int) {
+	cursor := NewCursor(editor)
+
+	editor.Cursors = append(editor.Cursors, cursor)
+	editor.lastEdit = EditNone
+
+	placeCursor(editor, cursor, x, y)
+
+	editor.syncCursors()
+}
+
+// SetCursors replaces the cursors with ones at the given x and y positions.
+// The last becomes the primary cursor.
+func (editor *Editor) SetCursors(positions [][2]int) {
+	for i, position := range(positions) {
+		if i == 0 {
+			editor.SetCursor(position[0], position[1])
+		} else {
+			editor.AddCursor(position[0], position[1])
+		}
+	}
+}
+
+// cursorPositions returns the x and y positions of the cursors, the primary
+// cursor last.
+func (editor *Editor) cursorPositions() [][2]int {
+	editor.syncCursors()
+
+	var positions [][2]int
+
+	for _, cursor := range(editor.Cursors) {
+		positions = append(positions, [2]int{cursor.X, cursor.Y})
+	}
+
+	return positions
+}
+
+func (editor *Editor) mergeCursors() {
+	var merged []*Cursor
+
+	for i := len(editor.Cursors) - 1; i >= 0; i-- {
+		cursor := editor.Cursors[i]
+
+		duplicate := false
+
+		for _, other := range(merged) {
+			if other.X == cursor.X && other.Y == cursor.Y {
+				duplicate = true
+				break
+			}
+		}
+
+		if duplicate {
+			cursor.CursorElement.Remove()
+		} else {
+			merged = append([]*Cursor{cursor}, merged...)
+		}
+	}
+
+	editor.Cursors = merged
+}
+
+func (editor *Editor) syncCursors() {
+	for _, cursor := range(editor.Cursors) {
+		syncCursor(editor, cursor)
+	}
+
+	editor.mergeCursors()
+}
+
+func (editor *Editor) Apply(action func(*Cursor)) {
+	for _, cursor := range(editor.Cursors) {
+		syncCursor(editor, cursor)
+		action(cursor)
+	}
+
+	editor.syncCursors()
+}
+
+func (editor *Editor) sortedCursors(reverse bool) []*Cursor {
+	cursors := append([]*Cursor{}, editor.Cursors...)
+
+	sort.SliceStable(cursors, func(i, j int) bool {
+		a, b := cursors[i], cursors[j]
+
+		if reverse {
+			a, b = b, a
+		}
+
+		return a.Y < b.Y || (a.Y == b.Y && a.X < b.X)
+	})
+
+	return cursors
+}
+
+func (editor *Editor) ApplyLines(reverse bool, action func(*Cursor)) {
+	editor.ClearBlock()
+	editor.syncCursors()
+
+	cursors := editor.sortedCursors(reverse)
+
+	rows := make([]*Element, len(cursors))
+	for i, cursor := range(cursors) {
+		rows[i] = cursor.CursorElement.Parent
+	}
+
+	done := map[*Element]bool{}
+
+	for i, cursor := range(cursors) {
+		if done[rows[i]] {
+			continue
+		}
+		done[rows[i]] = true
+
+		syncCursor(editor, cursor)
+		action(cursor)
+	}
+
+	editor.syncCursors()
+}
+
+// coveredLines returns the lines the block covers, or else the lines of the
+// cursors, in order.
+func (editor *Editor) coveredLines() []int {
+	if editor.Block != nil {
+		_, top, _, bottom := editor.Block.Bounds()
+
+		lines := []int{}
+		for y := top; y <= bottom; y++ {
+			lines = append(lines, y)
+		}
+
+		return lines
+	}
+
+	editor.syncCursors()
+
+	lines := []int{}
+	for _, cursor := range(editor.sortedCursors(false)) {
+		if len(lines) == 0 || lines[len(lines) - 1] != cursor.Y {
+			lines = append(lines, cursor.Y)
+		}
+	}
+
+	return lines
+}
+
+// MoveLines moves the covered lines up or down by offset together. Nothing
+// moves if one of them would leave the text.
+func (editor *Editor) MoveLines(offset int) {
+	lines := editor.coveredLines()
+	count := len(editor.TextArea.Children)
+
+	if lines[0] + offset < 0 || lines[len(lines) - 1] + offset >= count {
+		return
+	}
+
+	editor.RecordEdit(EditCommand)
+
+	// Move the line nearest the destination first, so that each one lands
+	// next to those already moved.
+	if offset > 0 {
+		for i, j := 0, len(lines) - 1; i < j; i, j = i + 1, j - 1 {
+			lines[i], lines[j] = lines[j], lines[i]
+		}
+	}
+
+	for _, y := range(lines) {
+		row := editor.TextArea.Children[y]
+		row.Remove()
+		editor.TextArea.InsertChild(row, y + offset)
+	}
+
+	if editor.Block != nil {
+		block := *editor.Block
+		editor.SetBlock(block.AnchorX, block.AnchorY + offset, block.X, block.Y + offset)
+	} else {
+		editor.syncCursors()
+	}
+}
+
+func (editor *Editor) AddCursorVertically(offset int) {
+	primary := editor.PrimaryCursor()
+
+	y := primary.Y + offset
+	if y < 0 || y >= len(editor.TextArea.Children) {
+		return
+	}
+
+	editor.AddCursor(primary.X, y)
+}
+
+func (editor *Editor) AddCursorAtNextOccurrence() {
+	editor.syncCursors()
+
+	primary := editor.PrimaryCursor()
+
+	text := lineText(editor.TextArea.Children[primary.Y])
+
+	start, end := primary.X, primary.X
+	for start > 0 && isWordChar(text[start - 1]) {
+		start--
+	}
+	for end < len(text) && isWordChar(text[end]) {
+		end++
+	}
+
+	if start == end {
+		return
+	}
+
+	word := string(text[start:end])
+	offset := primary.X - start
+
+	rows := len(editor.TextArea.Children)
+
+	for i := 0; i <= rows; i++ {
+		y := (primary.Y + i) % rows
+
+		line := lineText(editor.TextArea.Children[y])
+
+		from := 0
+		if i == 0 {
+			from = end
+		}
+
+		for x := from; x + len(word) <= len(line); x++ {
+			if i == rows && x >= start {
+				return
+			}
+
+			if string(line[x:x + len(word)]) != word {
+				continue
+			}
+
+			if (x > 0 && isWordChar(line[x - 1])) || (x + len(word) < len(line) && isWordChar(line[x + len(word)])) {
+				continue
+			}
+
+			occupied := false
+			for _, cursor := range(editor.Cursors) {
+				if cursor.Y == y && cursor.X == x + offset {
+					occupied = true
+					break
+				}
+			}
+
+			if !occupied {
+				editor.AddCursor(x + offset, y)
+				return
+			}
+		}
+	}
+}
+
+func (editor *Editor) MoveCursors(motion func(*Cursor) (int, int)) {
+	for _, cursor := range(editor.Cursors) {
+		syncCursor(editor, cursor)
+
+		x, y := motion(cursor)
+
+		placeCursor(editor, cursor, x, y)
+	}
+
+	editor.lastEdit = EditNone
+
+	editor.mergeCursors()
+}
+
+// HandlePointer places the cursors for the mouse events that bubble up to the
+// text area. Double and triple clicks select a word and a line, and dragging
+// with Alt held selects a block.
+func (editor *Editor) HandlePointer(event Event) {
+	switch e := event.(type) {
+	case *MouseButtonEvent:
+		if e.Button != 0 || e.Type != MouseButtonEventDown {
+			return
+		}
+
+		editor.ResetBlink()
+
+		x, y := editor.PositionAt(e.X, e.Y)
+
+		if e.Mod & sdl.KMOD_ALT != 0 {
+			editor.AddCursor(x, y)
+
+			editor.DragAnchorX, editor.DragAnchorY = x, y
+			return
+		}
+
+		switch e.Clicks {
+		case 2:
+			editor.SelectWord(x, y)
+		case 3:
+			editor.SelectLine(y)
+		default:
+			editor.SetCursor(x, y)
+		}
+	case *MouseDragEvent:
+		if e.Button != 0 {
+			return
+		}
+
+		switch e.Type {
+		case MouseDragEventStart:
+			editor.Dragging = e.Mod & sdl.KMOD_ALT != 0
+		case MouseDragEventMove:
+			if editor.Dragging {
+				x, y := editor.PositionAt(e.X, e.Y)
+
+				editor.SetBlock(editor.DragAnchorX, editor.DragAnchorY, x, y)
+				editor.revealPending = true
+			}
+		case MouseDragEventEnd:
+			editor.Dragging = false
+		}
+	}
+}
+
+// PositionAt returns the text position nearest to a point in window
+// coordinates. Points above or below the text go to its first or last row.
+func (editor *Editor) PositionAt(windowX, windowY int32) (int, int) {
+	area := editor.TextArea
+	areaX, areaY := area.Locate()
+
+	x, y := windowX - areaX, windowY - areaY
+
+	rows := area.Children
+	i := len(rows) - 1
+
+	for j, row := range(rows) {
+		if y < row.LastRenderedY + row.LastRenderedHeight {
+			i = j
+			break
+		}
+	}
+
+	row := rows[i]
+
+	return rowPositionAt(row, x - row.LastRenderedX, y - row.LastRenderedY), i
+}
+
+// rowPositionAt returns the position in row of the gap between glyphs nearest
+// to a point relative to it. A soft wrapped row has several lines, of which
+// the point is on the last one starting above it.
+func rowPositionAt(row *Element, x, y int32) int {
+	chars := rowChars(row)
+
+	if len(chars) == 0 {
+		return 0
+	}
+
+	lineY := chars[0].LastRenderedY
+
+	for _, char := range(chars) {
+		if char.LastRenderedY <= y {
+			lineY = char.LastRenderedY
+		}
+	}
+
+	for i, char := range(chars) {
+		if char.LastRenderedY < lineY {
+			continue
+		}
+
+		if char.LastRenderedY > lineY || x < char.LastRenderedX + char.LastRenderedWidth / 2 {
+			return i
+		}
+	}
+
+	return len(chars)
+}
+
+func (editor *Editor) SelectWord(x, y int) {
+	y = min(max(y, 0), len(editor.TextArea.Children) - 1)
+
+	start, end := wordAt(lineText(editor.TextArea.Children[y]), x)
+
+	editor.SetBlock(start, y, end, y)
+}
+
+func (editor *Editor) SelectLine(y int) {
+	y = min(max(y, 0), len(editor.TextArea.Children) - 1)
+
+	editor.SetBlock(0, y, rowLength(editor.TextArea.Children[y]), y)
+}
+
+func (editor *Editor) moveCursor(cursor *Cursor, code sdl.Keycode) (int, int) {
+	rows := editor.TextArea.Children
+
+	x, y := cursor.X, cursor.Y
+
+	switch code {
+	case sdl.K_RIGHT:
+		if x < rowLength(rows[y]) {
+			x++
+		} else if y + 1 < len(rows) {
+			y++
+			x = 0
+		}
+	case sdl.K_LEFT:
+		if x > 0 {
+			x--
+		} else if y > 0 {
+			y--
+			x = rowLength(rows[y])
+		}
+	case sdl.K_UP:
+		if editor.SoftWrap {
+			return editor.moveCursorVisually(cursor, -1)
+		}
+
+		if y > 0 {
+			y--
+		}
+	case sdl.K_DOWN:
+		if editor.SoftWrap {
+			return editor.moveCursorVisually(cursor, 1)
+		}
+
+		if y + 1 < len(rows) {
+			y++
+		}
+	}
+
+	return x, y
+}
+
+func (editor *Editor) HandleEvent(event Event) {
+	switch e := event.(type) {
+	case *TextEvent:
+		editor.revealPending = true
+		editor.ResetBlink()
+
+		editor.RecordEdit(EditTyping)
+
+		editor.CommitBlock()
+
+		editor.Apply(func(cursor *Cursor) {
+			writeChar(editor, cursor, e.Char)
+		})
+	case *KeyEvent:
+		if e.Type != sdl.KEYDOWN {
+			return
+		}
+
+		editor.revealPending = true
+		editor.ResetBlink()
+
+		ctrl, shift, alt := e.Mod & sdl.KMOD_CTRL != 0, e.Mod & sdl.KMOD_SHIFT != 0, e.Mod & sdl.KMOD_ALT != 0
+
+		switch e.Code {
+		case sdl.K_RETURN:
+			editor.RecordEdit(EditTyping)
+
+			editor.CommitBlock()
+
+			editor.Apply(func(cursor *Cursor) {
+				writeChar(editor, cursor, '\n')
+			})
+		case sdl.K_BACKSPACE:
+			editor.RecordEdit(EditDeleting)
+
+			if editor.Block != nil && editor.Block.X != editor.Block.AnchorX {
+				editor.CommitBlock()
+				return
+			}
+
+			editor.CommitBlock()
+
+			editor.Apply(func(cursor *Cursor) {
+				if ctrl {
+					deleteWordLeft(editor, cursor)
+				} else {
+					writeChar(editor, cursor, '\b')
+				}
+			})
+		case sdl.K_DELETE:
+			editor.RecordEdit(EditDeleting)
+
+			if editor.Block != nil && editor.Block.X != editor.Block.AnchorX {
+				editor.CommitBlock()
+				return
+			}
+
+			editor.CommitBlock()
+
+			editor.Apply(func(cursor *Cursor) {
+				if ctrl {
+					deleteWordRight(editor, cursor)
+				} else {
+					writeChar(editor, cursor, '\x7F')
+				}
+			})
+		case sdl.K_k:
+			if ctrl && shift {
+				editor.RecordEdit(EditCommand)
+
+				editor.ApplyLines(false, func(cursor *Cursor) {
+					deleteLine(editor, cursor)
+				})
+			}
+		case sdl.K_d:
+			if ctrl && shift {
+				editor.RecordEdit(EditCommand)
+
+				editor.ApplyLines(false, func(cursor *Cursor) {
+					duplicateLine(editor, cursor)
+				})
+			} else if ctrl {
+				editor.AddCursorAtNextOccurrence()
+			}
+		case sdl.K_c:
+			if ctrl {
+				editor.CopyBlock()
+			}
+		case sdl.K_x:
+			if ctrl && editor.Block != nil {
+				editor.RecordEdit(EditCommand)
+				editor.CutBlock()
+			}
+		case sdl.K_v:
+			if ctrl {
+				editor.RecordEdit(EditCommand)
+				editor.Paste()
+			}
+		case sdl.K_l:
+			if ctrl && shift {
+				editor.ClearBlock()
+			}
+		case sdl.K_j:
+			if !ctrl {
+				return
+			}
+
+			editor.RecordEdit(EditCommand)
+
+			if editor.Block != nil {
+				editor.JoinBlockLines()
+			} else {
+				editor.ApplyLines(true, func(cursor *Cursor) {
+					joinLines(editor, cursor)
+				})
+			}
+		case sdl.K_z:
+			if alt {
+				editor.SetSoftWrap(!editor.SoftWrap, editor.WrapColumn)
+			} else if ctrl && shift {
+				editor.Redo()
+			} else if ctrl {
+				editor.Undo()
+			}
+		case sdl.K_y:
+			if ctrl {
+				editor.Redo()
+			}
+		case sdl.K_F9:
+			// Without a block, the lines from the first cursor to the last
+			// are sorted.
+			lines := editor.coveredLines()
+
+			editor.RecordEdit(EditCommand)
+
+			sortLines(editor, lines[0], lines[len(lines) - 1] + 1, ctrl)
+			editor.updateBlock()
+		case sdl.K_TAB:
+			// Ctrl+Tab is left to move focus out of the text area.
+			if ctrl {
+				return
+			}
+
+			e.PreventDefault()
+
+			editor.RecordEdit(EditCommand)
+
+			if editor.Block != nil {
+				editor.IndentBlock(shift)
+			} else if shift {
+				editor.ApplyLines(false, func(cursor *Cursor) {
+					outdentLine(editor, cursor)
+				})
+			} else {
+				editor.CommitBlock()
+
+				editor.Apply(func(cursor *Cursor) {
+					indent(editor, cursor)
+				})
+			}
+		case sdl.K_ESCAPE:
+			primary := editor.PrimaryCursor()
+			editor.SetCursor(primary.X, primary.Y)
+		case sdl.K_RIGHT, sdl.K_LEFT, sdl.K_DOWN, sdl.K_UP:
+			if alt && shift {
+				editor.ExtendBlock(e.Code)
+			} else if (e.Code == sdl.K_UP || e.Code == sdl.K_DOWN) && (ctrl && alt) {
+				if e.Code == sdl.K_UP {
+					editor.AddCursorVertically(-1)
+				} else {
+					editor.AddCursorVertically(1)
+				}
+			} else if (e.Code == sdl.K_UP || e.Code == sdl.K_DOWN) && alt {
+				if e.Code == sdl.K_UP {
+					editor.MoveLines(-1)
+				} else {
+					editor.MoveLines(1)
+				}
+			} else {
+				editor.ClearBlock()
+
+				editor.MoveCursors(func(cursor *Cursor) (int, int) {
+					return editor.moveCursor(cursor, e.Code)
+				})
+			}
+		}
+	}
+}
+
+// RevealCursors scrolls each editor in the tree under root to its cursor.
+func RevealCursors(root *Element) {
+	root.eachEditor((*Editor).RevealCursor)
+}
+
+// eachEditor calls visit with every editor in the tree under e. Editors are
+// found through the elements they drive rather than kept in a list, so that
+// one whose element has left the tree, like the field of a closed dialog, is
+// simply no longer visited and can be collected.
+func (e *Element) eachEditor(visit func(*Editor)) {
+	if e.editor != nil {
+		visit(e.editor)
+
+		// The rows of a text area hold no further editors.
+		return
+	}
+
+	for _, child := range(e.Children) {
+		child.eachEditor(visit)
+	}
+
+	for _, popup := range(e.Popups) {
+		popup.eachEditor(visit)
+	}
+}
+
+func (editor *Editor) RevealCursor() {
+	if !editor.revealPending {
+		return
+	}
+
+	editor.revealPending = false
+
+	// Scrolling a virtualized area replaces estimated row heights with real
+	// ones, which can move the cursor again, so settle over a few passes.
+	for i := 0; i < 8 && editor.scrollToCursor(); i++ {
+		editor.TextArea.Arrange()
+	}
+}
+
+func (editor *Editor) scrollToCursor() bool {
+	area := editor.TextArea
+
+	oldX, oldY := area.ScrollPositionX, area.ScrollPositionY
+
+	cursorElement := editor.PrimaryCursor().CursorElement
+	row := cursorElement.Parent
+
+	// Rows outside the viewport are not arranged by a virtualized area.
+	row.Arrange()
+
+	x := row.LastRenderedX + area.ScrollPositionX + cursorElement.LastRenderedX
+	y := row.LastRenderedY + area.ScrollPositionY + cursorElement.LastRenderedY
+	w, h := cursorElement.LastRenderedWidth, cursorElement.LastRenderedHeight
+
+	var columnWidth int32 = 0
+	if editor.Font != nil {
+		columnWidth = charWidth(editor.Font)
+	}
+
+	marginX := min(int32(editor.ScrollMarginColumns) * columnWidth, max(area.LastRenderedWidth - w, 0) / 2)
+	marginY := min(int32(editor.ScrollMarginLines) * h, max(area.LastRenderedHeight - h, 0) / 2)
+
+	if y - marginY < area.ScrollPositionY {
+		area.ScrollPositionY = y - marginY
+	} else if y + h + marginY > area.ScrollPositionY + area.LastRenderedHeight {
+		area.ScrollPositionY = y + h + marginY - area.LastRenderedHeight
+	}
+
+	if area.ScrollX {
+		if x - marginX < area.ScrollPositionX {
+			area.ScrollPositionX = x - marginX
+		} else if x + w + marginX > area.ScrollPositionX + area.LastRenderedWidth {
+			area.ScrollPositionX = x + w + marginX - area.LastRenderedWidth
+		}
+	}
+
+	area.ClampScroll()
+
+	if area.ScrollPositionX == oldX && area.ScrollPositionY == oldY {
+		return false
+	}
+
+	area.Invalidate()
+
+	return true
+}
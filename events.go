@@ -0,0 +1,199 @@
+package main
+
+import (
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// Events are dispatched like in the DOM. Capture handlers run from the root
+// down to the target, then the target runs its handlers, then the event
+// bubbles back up through the event handlers of its ancestors.
+const (
+	PhaseNone = iota
+	PhaseCapture
+	PhaseTarget
+	PhaseBubble
+)
+
+type Event interface {
+	base() *EventBase
+}
+
+// EventBase is embedded in every event type and holds its dispatch state.
+type EventBase struct {
+	Target *Element
+	CurrentTarget *Element
+	Phase int
+
+	stopped bool
+	defaultPrevented bool
+}
+
+func (event *EventBase) base() *EventBase {
+	return event
+}
+
+// StopPropagation keeps the event from reaching any further element. The
+// other handlers of the current element still run.
+func (event *EventBase) StopPropagation() {
+	event.stopped = true
+}
+
+// PreventDefault cancels what the editor would otherwise do after the
+// dispatch, such as moving focus on a button press or on Tab.
+func (event *EventBase) PreventDefault() {
+	event.defaultPrevented = true
+}
+
+func (event *EventBase) DefaultPrevented() bool {
+	return event.defaultPrevented
+}
+
+type TextEvent struct {
+	EventBase
+
+	Char byte
+}
+
+type KeyEvent struct {
+	EventBase
+
+	Type uint32
+	Code sdl.Keycode
+	Mod uint16
+}
+
+// MouseHoverEvent is sent to each element the pointer enters or leaves. X and
+// Y are in window coordinates, LocalX and LocalY relative to the target.
+type MouseHoverEvent struct {
+	EventBase
+
+	Hovering bool
+
+	X int32
+	Y int32
+	LocalX int32
+	LocalY int32
+	Mod uint16
+}
+
+const (
+	MouseButtonEventDown = iota
+	MouseButtonEventUp
+	MouseButtonEventClick
+)
+
+// MouseButtonEvent targets the topmost element under the pointer, or the
+// element that captured it. Clicks counts the presses in quick succession, so
+// it is 2 for a double click.
+type MouseButtonEvent struct {
+	EventBase
+
+	Type int
+	Button int
+	Clicks int
+
+	X int32
+	Y int32
+	LocalX int32
+	LocalY int32
+	Mod uint16
+
+	Pointer *Pointer
+}
+
+const (
+	MouseDragEventStart = iota
+	MouseDragEventMove
+	MouseDragEventEnd
+)
+
+// MouseDragEvent targets the element a button was pressed on once the
+// pointer has moved away with the button held, which captures the pointer
+// until the button is released. StartX and StartY are where the button was
+// pressed, in window coordinates.
+type MouseDragEvent struct {
+	EventBase
+
+	Type int
+	Button int
+
+	X int32
+	Y int32
+	LocalX int32
+	LocalY int32
+	StartX int32
+	StartY int32
+	Mod uint16
+
+	Pointer *Pointer
+}
+
+// bubbles reports whether event goes back up after reaching its target. Hover,
+// focus and theme changes concern only the element they are sent to.
+func bubbles(event Event) bool {
+	switch event.(type) {
+	case *MouseHoverEvent, *FocusEvent, *ThemeEvent:
+		return false
+	}
+
+	return true
+}
+
+// AddEventHandler adds a handler that runs when an event reaches e or bubbles
+// up from one of its descendants.
+func (e *Element) AddEventHandler(handler func(Event)) {
+	e.EventHandlers = append(e.EventHandlers, handler)
+}
+
+// AddCaptureHandler adds a handler that runs before the event reaches any
+// descendant of e.
+func (e *Element) AddCaptureHandler(handler func(Event)) {
+	e.CaptureHandlers = append(e.CaptureHandlers, handler)
+}
+
+// Dispatch sends event to e through the capture, target and bubble phases. It
+// returns false if a handler called PreventDefault.
+func (e *Element) Dispatch(event Event) bool {
+	base := event.base()
+
+	base.Target = e
+
+	// The path is fixed before any handler runs, so that handlers removing
+	// elements do not change who receives the event.
+	var path []*Element
+
+	for element := e.Parent; element != nil; element = element.Parent {
+		path = append(path, element)
+	}
+
+	for i := len(path) - 1; i >= 0 && !base.stopped; i-- {
+		path[i].handle(event, path[i].CaptureHandlers, PhaseCapture)
+	}
+
+	if !base.stopped {
+		e.handle(event, e.CaptureHandlers, PhaseTarget)
+		e.handle(event, e.EventHandlers, PhaseTarget)
+	}
+
+	if bubbles(event) {
+		for i := 0; i < len(path) && !base.stopped; i++ {
+			path[i].handle(event, path[i].EventHandlers, PhaseBubble)
+		}
+	}
+
+	base.CurrentTarget = nil
+	base.Phase = PhaseNone
+
+	return !base.defaultPrevented
+}
+
+func (e *Element) handle(event Event, handlers []func(Event), phase int) {
+	base := event.base()
+
+	base.CurrentTarget = e
+	base.Phase = phase
+
+	for _, handler := range(handlers) {
+		handler(event)
+	}
+}
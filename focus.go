@@ -0,0 +1,238 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+var focusRingColor sdl.Color
+
+// FocusEvent is sent to an element when it gains or loses keyboard focus.
+type FocusEvent struct {
+	EventBase
+
+	Focused bool
+}
+
+// FocusManager tracks which Selectable element receives keyboard input. The
+// focused element is the one with Selected set.
+type FocusManager struct {
+	Root *Element
+	Focused *Element
+
+	// history holds previously focused elements, so that focus can go back
+	// to them when the element holding it is removed, such as a popup that
+	// closes.
+	history []*Element
+}
+
+const focusHistoryLength = 16
+
+func NewFocusManager(root *Element) *FocusManager {
+	return &FocusManager{
+		Root: root,
+	}
+}
+
+func (e *Element) Contains(other *Element) bool {
+	for ; other != nil; other = other.Parent {
+		if other == e {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (focus *FocusManager) Focus(element *Element) {
+	if element == focus.Focused {
+		return
+	}
+
+	previous := focus.Focused
+
+	if previous != nil {
+		focus.history = append(focus.history, previous)
+
+		if len(focus.history) > focusHistoryLength {
+			focus.history = focus.history[1:]
+		}
+
+		previous.SetSelected(false)
+		previous.Dispatch(&FocusEvent{Focused: false})
+	}
+
+	focus.Focused = element
+
+	if element != nil {
+		element.SetSelected(true)
+		element.Dispatch(&FocusEvent{Focused: true})
+	}
+}
+
+// scope is the part of the tree that focus may move in. A modal popup traps
+// focus inside it.
+func (focus *FocusManager) scope() *Element {
+	if modal := focus.Root.TopModal(); modal != nil {
+		return modal
+	}
+
+	return focus.Root
+}
+
+func collectFocusable(e *Element, elements []*Element) []*Element {
+	if e.Selectable && e.TabIndex >= 0 {
+		elements = append(elements, e)
+	}
+
+	for _, child := range(e.Children) {
+		elements = collectFocusable(child, elements)
+	}
+
+	for _, popup := range(e.Popups) {
+		elements = collectFocusable(popup, elements)
+	}
+
+	return elements
+}
+
+// Focusable returns the elements Tab moves through: those with a positive
+// TabIndex in ascending order, then the rest in tree order. Elements with a
+// negative TabIndex can only be focused by clicking them.
+func (focus *FocusManager) Focusable() []*Element {
+	elements := collectFocusable(focus.scope(), nil)
+
+	sort.SliceStable(elements, func(i, j int) bool {
+		a, b := elements[i].TabIndex, elements[j].TabIndex
+
+		if a == 0 || b == 0 {
+			return a != 0 && b == 0
+		}
+
+		return a < b
+	})
+
+	return elements
+}
+
+func (focus *FocusManager) move(offset int) {
+	elements := focus.Focusable()
+
+	if len(elements) == 0 {
+		return
+	}
+
+	current := -1
+
+	for i, element := range(elements) {
+		if element == focus.Focused {
+			current = i
+		}
+	}
+
+	if current < 0 {
+		if offset > 0 {
+			current = -1
+		} else {
+			current = len(elements)
+		}
+	}
+
+	focus.Focus(elements[(current + offset + len(elements)) % len(elements)])
+}
+
+func (focus *FocusManager) Next() {
+	focus.move(1)
+}
+
+func (focus *FocusManager) Previous() {
+	focus.move(-1)
+}
+
+// Update moves focus into a modal popup that has just opened, and back to the
+// previously focused element when the focused one leaves the tree.
+func (focus *FocusManager) Update() {
+	scope := focus.scope()
+
+	if focus.Focused != nil && scope.Contains(focus.Focused) {
+		return
+	}
+
+	if focus.Focused == nil && len(focus.history) == 0 {
+		return
+	}
+
+	if focus.Focused != nil && focus.Root.Contains(focus.Focused) {
+		// A modal popup opened over the focused element, which goes into the
+		// history to get focus back once the popup closes.
+		if elements := focus.Focusable(); len(elements) > 0 {
+			focus.Focus(elements[0])
+		} else {
+			focus.Focus(nil)
+		}
+
+		return
+	}
+
+	for len(focus.history) > 0 {
+		previous := focus.history[len(focus.history) - 1]
+
+		if focus.Root.Contains(previous) && !scope.Contains(previous) {
+			break
+		}
+
+		focus.history = focus.history[:len(focus.history) - 1]
+
+		if focus.Root.Contains(previous) {
+			focus.restore(previous)
+			return
+		}
+	}
+
+	if elements := focus.Focusable(); len(elements) > 0 {
+		focus.restore(elements[0])
+	} else {
+		focus.restore(nil)
+	}
+}
+
+// restore focuses element without remembering the element that lost focus,
+// which is gone from the tree.
+func (focus *FocusManager) restore(element *Element) {
+	focus.Focused = nil
+
+	focus.Focus(element)
+}
+
+func drawFocusRing(renderer *sdl.Renderer, dimensions *sdl.Rect, radius int32) {
+	renderer.SetDrawColor(focusRingColor.R, focusRingColor.G, focusRingColor.B, focusRingColor.A)
+
+	drawRoundedOutline(renderer, dimensions, radius, 2)
+}
+
+func drawRoundedOutline(renderer *sdl.Renderer, rect *sdl.Rect, radius, thickness int32) {
+	for k := int32(0); k < thickness; k++ {
+		x, y, w, h := rect.X + k, rect.Y + k, rect.W - 2 * k, rect.H - 2 * k
+		r := min(max(radius - k, 0), w / 2, h / 2)
+
+		renderer.DrawLine(x + r, y, x + w - 1 - r, y)
+		renderer.DrawLine(x + r, y + h - 1, x + w - 1 - r, y + h - 1)
+		renderer.DrawLine(x, y + r, x, y + h - 1 - r)
+		renderer.DrawLine(x + w - 1, y + r, x + w - 1, y + h - 1 - r)
+
+		steps := r * 2
+
+		for i := int32(0); i <= steps && r > 0; i++ {
+			angle := float64(i) / float64(steps) * math.Pi / 2
+
+			dx := int32(math.Round(float64(r) * math.Cos(angle)))
+			dy := int32(math.Round(float64(r) * math.Sin(angle)))
+
+			renderer.DrawPoint(x + r - dx, y + r - dy)
+			renderer.DrawPoint(x + w - 1 - r + dx, y + r - dy)
+			renderer.DrawPoint(x + r - dx, y + h - 1 - r + dy)
+			renderer.DrawPoint(x + w - 1 - r + dx, y + h - 1 - r + dy)
+		}
+	}
+}
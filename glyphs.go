@@ -0,0 +1,208 @@
+package main
+
+import (
+	"strings"
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/veandco/go-sdl2/ttf"
+)
+
+type glyphKey struct {
+	Font *ttf.Font
+	Color sdl.Color
+	Content string
+}
+
+// Glyph is a texture of rendered text. It is copied pixel for pixel, so that
+// text is never resampled at fractional scales.
+type Glyph struct {
+	Texture *sdl.Texture
+
+	PixelWidth int32
+	PixelHeight int32
+}
+
+var glyphCache = map[glyphKey]*Glyph{}
+
+type textSizeKey struct {
+	Font *ttf.Font
+	Content string
+}
+
+type textSize struct {
+	Width int32
+	Height int32
+}
+
+var textSizeCache = map[textSizeKey]textSize{}
+
+// measureText returns the size of content in font in logical units.
+func measureText(font *ttf.Font, content string) (int32, int32) {
+	key := textSizeKey{font, content}
+
+	if size, ok := textSizeCache[key]; ok {
+		return size.Width, size.Height
+	}
+
+	width, height, err := font.SizeUTF8(content)
+	if err != nil {
+		return 0, 0
+	}
+
+	size := textSize{toLogical(int32(width)), toLogical(int32(height))}
+
+	textSizeCache[key] = size
+
+	return size.Width, size.Height
+}
+
+// wrapText breaks content into lines no wider than width, in logical units,
+// at the spaces between words. A word wider than a line gets a line of its
+// own. It only measures, so wrapped text can be laid out without rendering.
+func wrapText(font *ttf.Font, content string, width int32) []string {
+	var lines []string
+
+	for _, paragraph := range(strings.Split(content, "\n")) {
+		line := ""
+
+		for _, word := range(strings.SplitAfter(paragraph, " ")) {
+			lineWidth, _ := measureText(font, strings.TrimRight(line + word, " "))
+
+			if line == "" || lineWidth <= width {
+				line += word
+				continue
+			}
+
+			lines = append(lines, strings.TrimRight(line, " "))
+			line = word
+		}
+
+		lines = append(lines, strings.TrimRight(line, " "))
+	}
+
+	return lines
+}
+
+// renderLines renders lines of text below each other, a line height apart.
+func renderLines(font *ttf.Font, lines []string, color sdl.Color) (*sdl.Surface, error) {
+	var surfaces []*sdl.Surface
+	var width int32
+
+	defer func() {
+		for _, surface := range(surfaces) {
+			if surface != nil {
+				surface.Free()
+			}
+		}
+	}()
+
+	for _, line := range(lines) {
+		if line == "" {
+			surfaces = append(surfaces, nil)
+			continue
+		}
+
+		surface, err := font.RenderUTF8Blended(line, color)
+		if err != nil {
+			return nil, err
+		}
+
+		surfaces = append(surfaces, surface)
+		width = max(width, surface.W)
+	}
+
+	lineHeight := int32(font.Height())
+
+	text, err := sdl.CreateRGBSurfaceWithFormat(0, max(width, 1), max(lineHeight * int32(len(lines)), 1), 32, sdl.PIXELFORMAT_ARGB8888)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, surface := range(surfaces) {
+		if surface == nil {
+			continue
+		}
+
+		// The lines are copied as they are, alpha included, rather than
+		// blended onto the empty surface.
+		surface.SetBlendMode(sdl.BLENDMODE_NONE)
+
+		err = surface.Blit(nil, text, &sdl.Rect{0, int32(i) * lineHeight, 0, 0})
+		if err != nil {
+			text.Free()
+			return nil, err
+		}
+	}
+
+	return text, nil
+}
+
+func (t *Text) Glyph(renderer *sdl.Renderer) (*Glyph, error) {
+	key := glyphKey{t.Font, t.Color, t.Content}
+
+	if glyph, ok := glyphCache[key]; ok {
+		return glyph, nil
+	}
+
+	surface, err := t.Font.RenderUTF8Blended(t.Content, t.Color)
+	if err != nil {
+		return nil, err
+	}
+
+	texture, err := renderer.CreateTextureFromSurface(surface)
+	if err != nil {
+		return nil, err
+	}
+
+	glyph := &Glyph{texture, surface.W, surface.H}
+
+	surface.Free()
+
+	glyphCache[key] = glyph
+
+	return glyph, nil
+}
+
+// clearTextCaches forgets every glyph and text size, which are only valid for
+// the size the fonts were opened at.
+func clearTextCaches() {
+	for _, glyph := range(glyphCache) {
+		glyph.Texture.Destroy()
+	}
+
+	glyphCache = map[glyphKey]*Glyph{}
+	textSizeCache = map[textSizeKey]textSize{}
+}
+
+// evictGlyphs destroys the glyphs drawn in a color that no text under root
+// uses any longer, like those of the theme before the current one.
+func evictGlyphs(root *Element) {
+	used := map[sdl.Color]bool{}
+	root.collectTextColors(used)
+
+	for key, glyph := range(glyphCache) {
+		if !used[key.Color] {
+			glyph.Texture.Destroy()
+			delete(glyphCache, key)
+		}
+	}
+}
+
+func (e *Element) collectTextColors(used map[sdl.Color]bool) {
+	if text, ok := e.Content.(*Text); ok {
+		used[text.Color] = true
+	}
+
+	for _, child := range(e.Children) {
+		child.collectTextColors(used)
+	}
+
+	for _, popup := range(e.Popups) {
+		popup.collectTextColors(used)
+	}
+}
+
+func (e *Element) IsGlyph() bool {
+	text, ok := e.Content.(*Text)
+
+	return ok && !text.Wrap && e.Width < 0 && e.Height < 0 && e.MinWidth == 0 && e.MinHeight == 0 && !e.Selected
+}
@@ -0,0 +1,545 @@
+package main
+
+import (
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+const (
+	LayoutFlow = iota
+	LayoutFlex
+)
+
+const (
+	DirectionRow = iota
+	DirectionColumn
+)
+
+const (
+	JustifyStart = iota
+	JustifyCenter
+	JustifyEnd
+	JustifySpaceBetween
+	JustifySpaceAround
+)
+
+const (
+	AlignStretch = iota
+	AlignStart
+	AlignCenter
+	AlignEnd
+)
+
+func (e *Element) margins(parentW, parentH int32) (int32, int32) {
+	marginX, marginY := e.MarginX, e.MarginY
+
+	if e.MarginXPercent {
+		marginX = e.MarginX * parentW / 100
+	}
+	if e.MarginYPercent {
+		marginY = e.MarginY * parentH / 100
+	}
+
+	return marginX, marginY
+}
+
+func (e *Element) NaturalSize() (int32, int32) {
+	width, height := e.DeclaredSize()
+
+	if width >= 0 && height >= 0 {
+		return width, height
+	}
+
+	var naturalWidth, naturalHeight int32
+
+	if e.Content != nil {
+		naturalWidth, naturalHeight = e.Content.Measure()
+	} else if e.Layout == LayoutFlex {
+		var count int32
+
+		for _, child := range(e.Children) {
+			childW, childH := child.NaturalSize()
+			marginX, marginY := child.margins(0, 0)
+
+			childW += marginX
+			childH += marginY
+
+			if e.Direction == DirectionColumn {
+				naturalWidth = max(naturalWidth, childW)
+				naturalHeight += childH
+			} else {
+				naturalWidth += childW
+				naturalHeight = max(naturalHeight, childH)
+			}
+
+			count++
+		}
+
+		if count > 1 {
+			if e.Direction == DirectionColumn {
+				naturalHeight += e.Gap * (count - 1)
+			} else {
+				naturalWidth += e.Gap * (count - 1)
+			}
+		}
+	} else if !e.Virtualize {
+		// A virtualized area is sized by its parent, and measuring every
+		// row it holds would defeat it.
+		wrapWidth := int32(-1)
+		if width >= 0 {
+			_, right, _, left := e.insets()
+			wrapWidth = width - left - right
+		}
+
+		flowWidth, flowHeight := e.naturalFlowSize(wrapWidth)
+
+		// Scrolling axes take no space of their own, otherwise a scrolled
+		// container would grow to the size of its content.
+		if !e.ScrollX {
+			naturalWidth = flowWidth
+		}
+		if !e.ScrollY {
+			naturalHeight = flowHeight
+		}
+	}
+
+	top, right, bottom, left := e.insets()
+
+	naturalWidth += left + right
+	naturalHeight += top + bottom
+
+	if width < 0 {
+		width = max(naturalWidth, e.MinWidth)
+	}
+	if height < 0 {
+		height = max(naturalHeight, e.MinHeight)
+	}
+
+	return width, height
+}
+
+// naturalFlowSize returns the size the children of a flow element take,
+// wrapped at wrapWidth unless it is negative, the way arrangeFlow places
+// them. The children are measured rather than taken from an earlier pass, so
+// that the layout of a frame does not depend on the one before.
+func (e *Element) naturalFlowSize(wrapWidth int32) (int32, int32) {
+	var width, height, lineWidth, lineHeight int32
+
+	for i, child := range(e.Children) {
+		if child.Overlay {
+			continue
+		}
+
+		childW, childH := child.NaturalSize()
+		marginX, marginY := child.margins(0, 0)
+
+		broken := i > 0 && e.Children[i - 1].Breaking
+		wraps := lineWidth + childW + marginX > wrapWidth && wrapWidth >= 0 && !e.ScrollX && !e.NoWrap
+
+		if (!child.Inline && wraps) || broken {
+			width = max(width, lineWidth)
+			height += lineHeight
+
+			lineWidth, lineHeight = 0, 0
+		}
+
+		lineWidth += childW + marginX
+		lineHeight = max(lineHeight, childH + marginY)
+	}
+
+	return max(width, lineWidth), height + lineHeight
+}
+
+func (e *Element) arrangeFlex(width, height int32) {
+	top, right, bottom, left := e.insets()
+
+	innerW := max(width - left - right, 0)
+	innerH := max(height - top - bottom, 0)
+
+	column := e.Direction == DirectionColumn
+
+	innerMain, innerCross := innerW, innerH
+	if column {
+		innerMain, innerCross = innerH, innerW
+	}
+
+	count := int32(len(e.Children))
+
+	mains := make([]int32, count)
+	crosses := make([]int32, count)
+	marginMains := make([]int32, count)
+	marginCrosses := make([]int32, count)
+	autoCross := make([]bool, count)
+
+	var used, totalGrow, totalShrink int32
+
+	for i, child := range(e.Children) {
+		child.LaidOut = false
+
+		childW, childH := child.NaturalSize()
+		marginX, marginY := child.margins(innerW, innerH)
+
+		if column {
+			mains[i], crosses[i] = childH, childW
+			marginMains[i], marginCrosses[i] = marginY, marginX
+			autoCross[i] = child.Width < 0
+		} else {
+			mains[i], crosses[i] = childW, childH
+			marginMains[i], marginCrosses[i] = marginX, marginY
+			autoCross[i] = child.Height < 0
+		}
+
+		used += mains[i] + marginMains[i]
+
+		totalGrow += child.Grow
+		totalShrink += child.Shrink
+	}
+
+	if count > 1 {
+		used += e.Gap * (count - 1)
+	}
+
+	free := innerMain - used
+
+	if free > 0 && totalGrow > 0 {
+		remaining := free
+
+		for i, child := range(e.Children) {
+			if child.Grow == 0 {
+				continue
+			}
+
+			share := free * child.Grow / totalGrow
+			mains[i] += share
+			remaining -= share
+		}
+
+		for i := count - 1; i >= 0; i-- {
+			if e.Children[i].Grow > 0 {
+				mains[i] += remaining
+				break
+			}
+		}
+
+		free = 0
+	} else if free < 0 && totalShrink > 0 {
+		for i, child := range(e.Children) {
+			if child.Shrink == 0 {
+				continue
+			}
+
+			minMain := child.MinWidth
+			if column {
+				minMain = child.MinHeight
+			}
+
+			mains[i] = max(mains[i] + free * child.Shrink / totalShrink, minMain)
+		}
+
+		free = 0
+	}
+
+	var offset, spacing int32
+
+	if free > 0 {
+		switch e.Justify {
+		case JustifyCenter:
+			offset = free / 2
+		case JustifyEnd:
+			offset = free
+		case JustifySpaceBetween:
+			if count > 1 {
+				spacing = free / (count - 1)
+			}
+		case JustifySpaceAround:
+			if count > 0 {
+				spacing = free / count
+				offset = spacing / 2
+			}
+		}
+	}
+
+	position := offset
+	if column {
+		position += top
+	} else {
+		position += left
+	}
+
+	var extentMain, extentCross int32
+
+	for i, child := range(e.Children) {
+		cross := crosses[i]
+
+		if e.Align == AlignStretch && autoCross[i] {
+			cross = max(innerCross - marginCrosses[i], 0)
+		}
+
+		crossPosition := marginCrosses[i]
+
+		switch e.Align {
+		case AlignCenter:
+			crossPosition = (innerCross - cross) / 2
+		case AlignEnd:
+			crossPosition = innerCross - cross - marginCrosses[i]
+		}
+
+		position += marginMains[i]
+
+		child.LaidOut = true
+
+		if column {
+			child.LayoutWidth, child.LayoutHeight = cross, mains[i]
+			child.LastRenderedX = left + crossPosition - e.ScrollPositionX
+			child.LastRenderedY = position - e.ScrollPositionY
+			child.LastRenderedMarginX, child.LastRenderedMarginY = marginCrosses[i], marginMains[i]
+		} else {
+			child.LayoutWidth, child.LayoutHeight = mains[i], cross
+			child.LastRenderedX = position - e.ScrollPositionX
+			child.LastRenderedY = top + crossPosition - e.ScrollPositionY
+			child.LastRenderedMarginX, child.LastRenderedMarginY = marginMains[i], marginCrosses[i]
+		}
+
+		position += mains[i]
+
+		extentMain = position
+		extentCross = max(extentCross, crossPosition + cross)
+
+		position += e.Gap + spacing
+	}
+
+	if column {
+		e.LastRenderedChildWidth, e.LastRenderedChildHeight = extentCross + left + right, extentMain + bottom
+	} else {
+		e.LastRenderedChildWidth, e.LastRenderedChildHeight = extentMain + right, extentCross + top + bottom
+	}
+}
+
+// Arrange computes the size and position of e and its descendants for the
+// current state of the tree. It only measures content and never renders, so
+// it can run without a renderer; Render paints the result.
+func (e *Element) Arrange() (int32, int32) {
+	realWidth, realHeight := e.ExpandedSize()
+
+	if !e.Dirty && e.Arranged && realWidth == e.ArrangedExpandedWidth && realHeight == e.ArrangedExpandedHeight {
+		return e.LastRenderedWidth, e.LastRenderedHeight
+	}
+
+	if !e.ScrollX {
+		e.ScrollPositionX = 0
+	}
+	if !e.ScrollY {
+		e.ScrollPositionY = 0
+	}
+
+	e.Wraps = nil
+
+	scrollbar := e.overflows(true)
+
+	e.arrangeLayout(realWidth, realHeight)
+
+	// Content that shrank can leave e scrolled past its end, and a vertical
+	// scrollbar that comes or goes changes the room its children have, so
+	// either lays e out again.
+	if (e.ScrollX || e.ScrollY) && (e.clampScrollPosition() || e.overflows(true) != scrollbar) {
+		e.Wraps = nil
+
+		e.arrangeLayout(realWidth, realHeight)
+	}
+
+	e.arrangePopups()
+
+	e.Arranged = true
+	e.ArrangedExpandedWidth = realWidth
+	e.ArrangedExpandedHeight = realHeight
+
+	return e.LastRenderedWidth, e.LastRenderedHeight
+}
+
+func (e *Element) arrangeLayout(realWidth, realHeight int32) {
+	if e.Content != nil {
+		e.arrangeContent(realWidth, realHeight)
+	} else if e.Layout == LayoutFlex {
+		e.arrangeFlexContainer(realWidth, realHeight)
+	} else if e.Virtualize && realHeight >= 0 {
+		e.arrangeVirtualized(realWidth, realHeight)
+	} else {
+		e.arrangeFlow(realWidth, realHeight)
+	}
+}
+
+func (e *Element) arrangeContent(realWidth, realHeight int32) {
+	width, height := realWidth, realHeight
+
+	if width < 0 || height < 0 {
+		top, right, bottom, left := e.insets()
+
+		contentWidth, contentHeight := e.Content.Measure()
+
+		if width < 0 {
+			width = contentWidth + left + right
+		}
+		if height < 0 {
+			height = contentHeight + top + bottom
+		}
+	}
+
+	e.LastRenderedWidth = max(width, e.MinWidth)
+	e.LastRenderedHeight = max(height, e.MinHeight)
+}
+
+func (e *Element) arrangeFlexContainer(realWidth, realHeight int32) {
+	width, height := realWidth, realHeight
+
+	if width < 0 || height < 0 {
+		width, height = e.NaturalSize()
+	}
+
+	width = max(width, e.MinWidth, 1)
+	height = max(height, e.MinHeight, 1)
+
+	e.arrangeFlex(width, height)
+
+	for _, child := range(e.Children) {
+		child.Arrange()
+	}
+
+	e.LastRenderedWidth = width
+	e.LastRenderedHeight = height
+}
+
+func (e *Element) arrangeVirtualized(realWidth, realHeight int32) {
+	top, right, bottom, left := e.insets()
+
+	innerHeight := realHeight - top - bottom
+
+	var currentY, childWidth int32
+
+	// Children that were never arranged are assumed to be as tall as the
+	// average of those that were.
+	var knownHeight, known int32
+
+	for _, child := range(e.Children) {
+		if child.LastRenderedHeight != 0 {
+			knownHeight += child.LastRenderedHeight
+			known++
+		}
+	}
+
+	if known > 0 {
+		e.EstimatedChildHeight = knownHeight / known
+	}
+
+	for _, child := range(e.Children) {
+		height := child.LastRenderedHeight
+		if height == 0 {
+			height = max(child.MinHeight, e.EstimatedChildHeight)
+		}
+
+		child.LastRenderedMarginX = 0
+		child.LastRenderedMarginY = 0
+		child.LastRenderedX = left - e.ScrollPositionX
+		child.LastRenderedY = top + currentY - e.ScrollPositionY
+
+		if currentY + height > e.ScrollPositionY && currentY < e.ScrollPositionY + innerHeight {
+			_, height = child.Arrange()
+		} else if child.CachedTexture != nil && (currentY + height < e.ScrollPositionY - innerHeight || currentY > e.ScrollPositionY + 2 * innerHeight) {
+			// Rows more than a viewport away from the visible ones give up
+			// their textures, so that memory does not grow with every line
+			// scrolled past. Rows just out of view keep theirs for when
+			// they scroll back.
+			child.ReleaseTextures()
+		}
+
+		if child.LastRenderedWidth > childWidth {
+			childWidth = child.LastRenderedWidth
+		}
+
+		currentY += height
+	}
+
+	e.LastRenderedChildWidth = childWidth + left + right
+	e.LastRenderedChildHeight = currentY + top + bottom
+
+	e.LastRenderedWidth = max(realWidth, e.MinWidth)
+	e.LastRenderedHeight = max(realHeight, e.MinHeight)
+}
+
+func (e *Element) arrangeFlow(realWidth, realHeight int32) {
+	top, right, bottom, left := e.insets()
+
+	maxWidth := realWidth
+	if realWidth >= 0 {
+		maxWidth = realWidth - left - right
+	}
+
+	var currentX, currentY int32
+
+	var currentLineHeight int32
+
+	var childWidth int32
+
+	for i, child := range(e.Children) {
+		width, height := child.Arrange()
+
+		if child.Overlay {
+			child.LastRenderedX = left + currentX - e.ScrollPositionX
+			child.LastRenderedY = top + currentY - e.ScrollPositionY
+
+			continue
+		}
+
+		child.LastRenderedMarginX, child.LastRenderedMarginY = child.margins(realWidth, realHeight)
+
+		totalHeight := height + child.LastRenderedMarginY
+
+		newX := currentX + width + child.LastRenderedMarginX
+
+		broken := i > 0 && e.Children[i - 1].Breaking
+
+		if !child.Inline && (newX > maxWidth && maxWidth >= 0 && !e.ScrollX && !e.NoWrap) || broken {
+			if currentX > childWidth {
+				childWidth = currentX
+			}
+
+			if e.WrapIndicator && !broken {
+				e.Wraps = append(e.Wraps, sdl.Rect{0, top + currentY, 0, currentLineHeight})
+			}
+
+			currentX = 0
+			newX = currentX + width
+
+			currentY += currentLineHeight
+			currentLineHeight = 0
+		}
+
+		if totalHeight > currentLineHeight {
+			currentLineHeight = totalHeight
+		}
+
+		child.LastRenderedX = left + currentX + child.LastRenderedMarginX - e.ScrollPositionX
+		child.LastRenderedY = top + currentY + child.LastRenderedMarginY - e.ScrollPositionY
+
+		currentX = newX
+	}
+
+	if currentX > childWidth {
+		childWidth = currentX
+	}
+
+	currentY += currentLineHeight
+
+	e.LastRenderedChildWidth = childWidth + left + right
+	e.LastRenderedChildHeight = currentY + top + bottom
+
+	width, height := realWidth, realHeight
+
+	if width < 0 {
+		width = e.LastRenderedChildWidth
+	}
+	if height < 0 {
+		height = e.LastRenderedChildHeight
+	}
+
+	e.LastRenderedWidth = max(width, e.MinWidth)
+	e.LastRenderedHeight = max(height, e.MinHeight)
+}
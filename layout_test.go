@@ -0,0 +1,325 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"github.com/veandco/go-sdl2/ttf"
+)
+
+// box returns an empty element of a fixed size, which needs no font or
+// renderer to lay out.
+func box(width, height int32) *Element {
+	return &Element{
+		Width: width,
+		Height: height,
+	}
+}
+
+func container(layout int, width, height int32, children ...*Element) *Element {
+	e := &Element{
+		Width: width,
+		Height: height,
+
+		Layout: layout,
+	}
+
+	for _, child := range(children) {
+		e.AppendChild(child)
+	}
+
+	return e
+}
+
+type placement struct {
+	X int32
+	Y int32
+	Width int32
+	Height int32
+}
+
+func placements(e *Element) []placement {
+	var result []placement
+
+	for _, child := range(e.Children) {
+		result = append(result, placement{child.LastRenderedX, child.LastRenderedY, child.LastRenderedWidth, child.LastRenderedHeight})
+	}
+
+	return result
+}
+
+func checkPlacements(t *testing.T, name string, e *Element, want []placement) {
+	t.Helper()
+
+	got := placements(e)
+
+	if len(got) != len(want) {
+		t.Fatalf("%s: %d children, want %d", name, len(got), len(want))
+	}
+
+	for i := range(want) {
+		if got[i] != want[i] {
+			t.Errorf("%s: child %d at %+v, want %+v", name, i, got[i], want[i])
+		}
+	}
+}
+
+func TestArrangeFlex(t *testing.T) {
+	tests := []struct {
+		name string
+		build func() *Element
+		want []placement
+	}{
+		{
+			"row with a gap",
+			func() *Element {
+				e := container(LayoutFlex, 300, 100, box(50, 20), box(50, 20), box(50, 20))
+				e.Gap = 10
+				e.Align = AlignStart
+				return e
+			},
+			[]placement{{0, 0, 50, 20}, {60, 0, 50, 20}, {120, 0, 50, 20}},
+		},
+		{
+			"grow shares the free space and gives the remainder to the last",
+			func() *Element {
+				a, b := box(50, 20), box(50, 20)
+				a.Grow, b.Grow = 1, 2
+				e := container(LayoutFlex, 350, 100, a, b)
+				e.Align = AlignStart
+				return e
+			},
+			[]placement{{0, 0, 133, 20}, {133, 0, 217, 20}},
+		},
+		{
+			"shrink down to the minimum",
+			func() *Element {
+				a, b := box(80, 20), box(80, 20)
+				a.Shrink, b.Shrink = 1, 1
+				b.MinWidth = 60
+				e := container(LayoutFlex, 100, 100, a, b)
+				e.Align = AlignStart
+				return e
+			},
+			[]placement{{0, 0, 50, 20}, {50, 0, 60, 20}},
+		},
+		{
+			"justify center",
+			func() *Element {
+				e := container(LayoutFlex, 300, 100, box(50, 20), box(50, 20))
+				e.Justify, e.Align = JustifyCenter, AlignStart
+				return e
+			},
+			[]placement{{100, 0, 50, 20}, {150, 0, 50, 20}},
+		},
+		{
+			"justify end",
+			func() *Element {
+				e := container(LayoutFlex, 300, 100, box(50, 20), box(50, 20))
+				e.Justify, e.Align = JustifyEnd, AlignStart
+				return e
+			},
+			[]placement{{200, 0, 50, 20}, {250, 0, 50, 20}},
+		},
+		{
+			"justify space between",
+			func() *Element {
+				e := container(LayoutFlex, 300, 100, box(50, 20), box(50, 20))
+				e.Justify, e.Align = JustifySpaceBetween, AlignStart
+				return e
+			},
+			[]placement{{0, 0, 50, 20}, {250, 0, 50, 20}},
+		},
+		{
+			"stretch fills the cross axis of auto sized children",
+			func() *Element {
+				return container(LayoutFlex, 300, 100, box(50, -1), box(50, 20))
+			},
+			[]placement{{0, 0, 50, 100}, {50, 0, 50, 20}},
+		},
+		{
+			"align center",
+			func() *Element {
+				e := container(LayoutFlex, 300, 100, box(50, 20))
+				e.Align = AlignCenter
+				return e
+			},
+			[]placement{{0, 40, 50, 20}},
+		},
+		{
+			"column inside padding",
+			func() *Element {
+				e := container(LayoutFlex, 300, 100, box(50, 20), box(50, 20))
+				e.Direction, e.Align = DirectionColumn, AlignStart
+				e.PaddingTop, e.PaddingLeft = 5, 7
+				return e
+			},
+			[]placement{{7, 5, 50, 20}, {7, 25, 50, 20}},
+		},
+	}
+
+	for _, test := range(tests) {
+		e := test.build()
+		e.Arrange()
+
+		checkPlacements(t, test.name, e, test.want)
+	}
+}
+
+func TestArrangeFlexNaturalSize(t *testing.T) {
+	e := container(LayoutFlex, -1, -1, box(50, 20), box(30, 40))
+	e.Gap = 5
+	e.PaddingLeft, e.PaddingRight = 2, 3
+
+	if width, height := e.Arrange(); width != 90 || height != 40 {
+		t.Errorf("auto sized row is %dx%d, want 90x40", width, height)
+	}
+}
+
+func TestArrangeFlowInFlex(t *testing.T) {
+	flow := container(LayoutFlow, -1, -1, box(40, 10), box(40, 10))
+
+	e := container(LayoutFlex, -1, -1, flow)
+	e.Direction = DirectionColumn
+
+	// The layout must not depend on what an earlier pass left behind.
+	for pass := 1; pass <= 2; pass++ {
+		width, height := e.Arrange()
+
+		if width != 80 || height != 10 {
+			t.Errorf("pass %d: column is %dx%d, want 80x10", pass, width, height)
+		}
+
+		checkPlacements(t, "flow in a column", e, []placement{{0, 0, 80, 10}})
+		checkPlacements(t, "children of the flow", flow, []placement{{0, 0, 40, 10}, {40, 0, 40, 10}})
+	}
+}
+
+func TestArrangeFlow(t *testing.T) {
+	tests := []struct {
+		name string
+		build func() *Element
+		want []placement
+		width int32
+		height int32
+	}{
+		{
+			"wraps at the width",
+			func() *Element {
+				return container(LayoutFlow, 100, -1, box(40, 10), box(40, 10), box(40, 10))
+			},
+			[]placement{{0, 0, 40, 10}, {40, 0, 40, 10}, {0, 10, 40, 10}},
+			100, 20,
+		},
+		{
+			"breaks after a breaking child",
+			func() *Element {
+				first := box(20, 10)
+				first.Breaking = true
+				return container(LayoutFlow, 100, -1, first, box(20, 10))
+			},
+			[]placement{{0, 0, 20, 10}, {0, 10, 20, 10}},
+			100, 20,
+		},
+		{
+			"a line is as tall as its tallest child",
+			func() *Element {
+				return container(LayoutFlow, 100, -1, box(40, 10), box(40, 30), box(40, 10))
+			},
+			[]placement{{0, 0, 40, 10}, {40, 0, 40, 30}, {0, 30, 40, 10}},
+			100, 40,
+		},
+		{
+			"no wrap keeps one line",
+			func() *Element {
+				e := container(LayoutFlow, 100, -1, box(40, 10), box(40, 10), box(40, 10))
+				e.NoWrap = true
+				return e
+			},
+			[]placement{{0, 0, 40, 10}, {40, 0, 40, 10}, {80, 0, 40, 10}},
+			100, 10,
+		},
+		{
+			"auto width takes the content",
+			func() *Element {
+				return container(LayoutFlow, -1, -1, box(40, 10), box(30, 10))
+			},
+			[]placement{{0, 0, 40, 10}, {40, 0, 30, 10}},
+			70, 10,
+		},
+		{
+			"margins and padding",
+			func() *Element {
+				child := box(40, 10)
+				child.MarginX, child.MarginY = 5, 3
+				e := container(LayoutFlow, 100, -1, child)
+				e.PaddingTop, e.PaddingLeft = 2, 4
+				return e
+			},
+			[]placement{{9, 5, 40, 10}},
+			100, 15,
+		},
+		{
+			"scrolled content moves",
+			func() *Element {
+				e := container(LayoutFlow, 100, 20, box(40, 10), box(40, 10), box(40, 10), box(40, 10), box(40, 10))
+				e.ScrollY = true
+				e.ScrollPositionY = 5
+				return e
+			},
+			[]placement{{0, -5, 40, 10}, {40, -5, 40, 10}, {0, 5, 40, 10}, {40, 5, 40, 10}, {0, 15, 40, 10}},
+			100, 20,
+		},
+	}
+
+	for _, test := range(tests) {
+		e := test.build()
+
+		width, height := e.Arrange()
+
+		checkPlacements(t, test.name, e, test.want)
+
+		if width != test.width || height != test.height {
+			t.Errorf("%s: %dx%d, want %dx%d", test.name, width, height, test.width, test.height)
+		}
+	}
+}
+
+func TestWrappedTextMeasure(t *testing.T) {
+	err := ttf.Init()
+	if err != nil {
+		t.Skip(err)
+	}
+
+	font, err := ttf.OpenFont(fontPath, fontSize)
+	if err != nil {
+		t.Skip(err)
+	}
+
+	defer font.Close()
+
+	spaceWidth, _ := measureText(font, "aaaa aaaa")
+	wordWidth, _ := measureText(font, "aaaa")
+
+	e := box(spaceWidth - 1, -1)
+
+	text := &Text{
+		Content: "aaaa aaaa aaaa\nbb",
+		Font: font,
+		Wrap: true,
+	}
+
+	e.SetContent(text)
+
+	lines := wrapText(font, text.Content, e.Width)
+	want := []string{"aaaa", "aaaa", "aaaa", "bb"}
+
+	if strings.Join(lines, "|") != strings.Join(want, "|") {
+		t.Errorf("wrapped into %q, want %q", lines, want)
+	}
+
+	width, height := text.Measure()
+
+	if width != wordWidth || height != 4 * fontHeight(font) {
+		t.Errorf("wrapped text measures %dx%d, want %dx%d", width, height, wordWidth, 4 * fontHeight(font))
+	}
+}
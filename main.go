@@ -2,7 +2,10 @@ package main
 
 import (
 	"errors"
+	"flag"
+	"fmt"
 	"os"
+	"time"
 	"github.com/veandco/go-sdl2/sdl"
 	"github.com/veandco/go-sdl2/ttf"
 	"github.com/veandco/go-sdl2/img"
@@ -15,45 +18,26 @@ type Cursor struct {
 	Y int
 }
 
-func NewCursor() *Cursor {
-	return &Cursor{
+func NewCursor(editor *Editor) *Cursor {
+	cursor := &Cursor{
 		&Element{
-			Width: 5,
-			Height: 40,
-			HeightPercent: false,
-
-			BackgroundColor: sdl.Color{0, 255, 255, 0},
+			Overlay: true,
 		},
 
 		0,
 		0,
 	}
-}
-
-type Event interface{}
-
-type TextEvent byte
 
-type KeyEvent struct {
-	Type uint32
-	Code sdl.Keycode
-}
-
-type MouseHoverEvent bool
+	cursor.CursorElement.Painter = editor.paintCursor
 
-const (
-	MouseButtonEventDown = iota
-	MouseButtonEventUp
-	MouseButtonEventClick
-)
+	editor.sizeCursor(cursor)
 
-type MouseButtonEvent struct {
-	Type int
-	Button int
+	return cursor
 }
 
 type ElementContent interface {
 	SetContainer(*Element)
+	Measure() (int32, int32)
 	Render(*sdl.Renderer) (*sdl.Texture, error)
 	Destroy() error
 }
@@ -72,6 +56,25 @@ func (t *Text) SetContainer(e *Element) {
 	t.Container = e
 }
 
+func (t *Text) Measure() (int32, int32) {
+	parentW, _ := t.Container.ExpandedSize()
+
+	if t.Wrap && parentW >= 0 {
+		var width int32
+
+		lines := wrapText(t.Font, t.Content, parentW)
+
+		for _, line := range(lines) {
+			lineWidth, _ := measureText(t.Font, line)
+			width = max(width, lineWidth)
+		}
+
+		return width, int32(len(lines)) * fontHeight(t.Font)
+	}
+
+	return measureText(t.Font, t.Content)
+}
+
 func (t *Text) Render(renderer *sdl.Renderer) (*sdl.Texture, error) {
 	var surface *sdl.Surface
 
@@ -80,7 +83,7 @@ func (t *Text) Render(renderer *sdl.Renderer) (*sdl.Texture, error) {
 	var err error
 
 	if t.Wrap && parentW >= 0 {
-		surface, err = t.Font.RenderUTF8BlendedWrapped(t.Content, t.Color, int(parentW))
+		surface, err = renderLines(t.Font, wrapText(t.Font, t.Content, parentW), t.Color)
 	} else {
 		surface, err = t.Font.RenderUTF8Blended(t.Content, t.Color)
 	}
@@ -113,6 +116,10 @@ func (image *Image) SetContainer(e *Element) {
 	image.Container = e;
 }
 
+func (image *Image) Measure() (int32, int32) {
+	return image.ImageSurface.W, image.ImageSurface.H
+}
+
 func (image *Image) Render(renderer *sdl.Renderer) (*sdl.Texture, error) {
 	texture, err := renderer.CreateTextureFromSurface(image.ImageSurface)
 	if err != nil {
@@ -141,33 +148,102 @@ type Element struct {
 	MarginY int32
 	MarginYPercent bool
 
+	Layout int
+	Direction int
+	Justify int
+	Align int
+	Gap int32
+	Grow int32
+	Shrink int32
+
+	PaddingTop int32
+	PaddingRight int32
+	PaddingBottom int32
+	PaddingLeft int32
+
+	BorderWidth int32
+	BorderColor sdl.Color
+	CornerRadius int32
+
+	ShadowColor sdl.Color
+	ShadowOffsetX int32
+	ShadowOffsetY int32
+	ShadowBlur int32
+
+	LaidOut bool
+	LayoutWidth int32
+	LayoutHeight int32
+
 	Breaking bool
 	Inline bool
+	NoWrap bool
+	WrapIndicator bool
+
+	TextRun bool
+
+	Overlay bool
+	Painter func(*sdl.Renderer, *sdl.Rect)
 
 	BackgroundColor sdl.Color
 
+	// Opacity scales the alpha of the whole element, from 0, invisible, to
+	// 1. It only applies once OpacitySet is true, so that elements that never
+	// set it are opaque.
+	Opacity float32
+	OpacitySet bool
+
 	ScrollX bool
 	ScrollPositionX int32
 	ScrollY bool
 	ScrollPositionY int32
 
+	scrollAnimation *scrollAnimation
+	scrollbarDragged bool
+
+	Virtualize bool
+	EstimatedChildHeight int32
+
+	Popups []*Element
+	PositionX int32
+	PositionY int32
+	ZIndex int
+	Modal bool
+	Centered bool
+
 	Parent *Element
 	Children []*Element
 
 	Content ElementContent
 
+	// editor is the editor driving this element, if it is a text area.
+	editor *Editor
+
 	EventHandlers []func(Event)
+	CaptureHandlers []func(Event)
 
 	IsTextInput bool
 
 	Selectable bool
 	Selected bool
+	TabIndex int
+	HideFocusRing bool
 
 	MouseHovering bool
 	MouseButtonClicks [3]bool
 
 	RenderingTexture *sdl.Texture
 
+	Dirty bool
+	CachedTexture *sdl.Texture
+	CachedWidth int32
+	CachedHeight int32
+
+	Arranged bool
+	ArrangedExpandedWidth int32
+	ArrangedExpandedHeight int32
+
+	Wraps []sdl.Rect
+
 	LastRenderedX int32
 	LastRenderedY int32
 	LastRenderedWidth int32
@@ -199,6 +275,14 @@ func (e *Element) Destroy() error {
 }
 
 func (e *Element) ExpandedSize() (int32, int32) {
+	if e.LaidOut {
+		return e.LayoutWidth, e.LayoutHeight
+	}
+
+	return e.DeclaredSize()
+}
+
+func (e *Element) DeclaredSize() (int32, int32) {
 	if !e.WidthPercent && !e.HeightPercent {
 		return e.Width, e.Height
 	}
@@ -208,7 +292,7 @@ func (e *Element) ExpandedSize() (int32, int32) {
 	var parentW, parentH int32 = 0, 0
 
 	if e.Parent != nil {
-		parentW, parentH = e.Parent.ExpandedSize()
+		parentW, parentH = e.Parent.contentSize()
 	}
 
 	if e.WidthPercent {
@@ -222,336 +306,349 @@ func (e *Element) ExpandedSize() (int32, int32) {
 	return realWidth, realHeight
 }
 
-func drawSelectionBorder(renderer *sdl.Renderer, dimensions *sdl.Rect) {
-	renderer.SetDrawColor(0, 255, 255, 255)
-	drawThickRect(renderer, dimensions, 1)
+// contentSize returns the space inside the border and padding of e that its
+// children share, which leaves out a vertical scrollbar so that no content
+// ends up underneath it. An axis of unknown size stays negative.
+func (e *Element) contentSize() (int32, int32) {
+	width, height := e.ExpandedSize()
+	top, right, bottom, left := e.insets()
+
+	if width >= 0 {
+		width = max(width - left - right, 0)
+
+		if e.overflows(true) {
+			width = max(width - scrollbarWidth, 0)
+		}
+	}
+
+	if height >= 0 {
+		height = max(height - top - bottom, 0)
+	}
+
+	return width, height
+}
+
+func drawWrapIndicator(renderer *sdl.Renderer, dimensions *sdl.Rect) {
+	renderer.SetDrawColor(wrapIndicatorColor.R, wrapIndicatorColor.G, wrapIndicatorColor.B, wrapIndicatorColor.A)
+	renderer.FillRect(dimensions)
+}
+
+func (e *Element) Invalidate() {
+	for element := e; element != nil; element = element.Parent {
+		element.Dirty = true
+	}
+}
+
+// InvalidateTree marks e and everything under it to be laid out and painted
+// from scratch, for when something all of them depend on, like the scale,
+// has changed.
+func (e *Element) InvalidateTree() {
+	e.ReleaseTextures()
+	e.Invalidate()
+
+	e.markTreeDirty()
 }
 
+func (e *Element) markTreeDirty() {
+	e.Dirty = true
+
+	for _, child := range(e.Children) {
+		child.markTreeDirty()
+	}
+
+	for _, popup := range(e.Popups) {
+		popup.markTreeDirty()
+	}
+}
+
+func (e *Element) ReleaseTextures() {
+	if e.CachedTexture != nil {
+		e.CachedTexture.Destroy()
+		e.CachedTexture = nil
+	}
+
+	for _, child := range(e.Children) {
+		child.ReleaseTextures()
+	}
+
+	for _, popup := range(e.Popups) {
+		popup.ReleaseTextures()
+	}
+}
+
+// Render paints e and its descendants at the sizes and positions computed by
+// the last Arrange.
 func (e *Element) Render(renderer *sdl.Renderer) (*sdl.Texture, error) {
-	realWidth, realHeight := e.ExpandedSize()
+	width, height := e.LastRenderedWidth, e.LastRenderedHeight
 
-	if e.Content != nil {
-		e.Children = nil
+	if !e.Dirty && e.CachedTexture != nil && width == e.CachedWidth && height == e.CachedHeight {
+		return e.CachedTexture, nil
+	}
 
-		contentTexture, err := e.Content.Render(renderer)
-		if err != nil {
-			return nil, err
-		}
+	texture, err := e.paint(renderer, max(width, 1), max(height, 1))
+	if err != nil {
+		return nil, err
+	}
 
-		_, _, contentWidth, contentHeight, err := contentTexture.Query()
-		if err != nil {
-			return nil, err
-		}
+	if e.CachedTexture != nil {
+		e.CachedTexture.Destroy()
+	}
 
-		textureWidth, textureHeight := contentWidth, contentHeight
+	e.CachedTexture = texture
+	e.CachedWidth = width
+	e.CachedHeight = height
 
-		if realWidth >= 0 {
-			textureWidth = realWidth
-		}
-		if realHeight >= 0 {
-			textureHeight = realHeight
-		}
+	e.Dirty = false
 
-		if textureWidth < e.MinWidth {
-			textureWidth = e.MinWidth
-		}
-		if textureHeight < e.MinHeight {
-			textureWidth = e.MinHeight
-		}
+	return texture, nil
+}
 
-		elementTexture, err := renderer.CreateTexture(sdl.PIXELFORMAT_RGB24, sdl.TEXTUREACCESS_TARGET, textureWidth, textureHeight)
-		if err != nil {
-			return nil, err
-		}
+func (e *Element) intersects(width, height int32) bool {
+	return e.LastRenderedX < width && e.LastRenderedX + e.LastRenderedWidth > 0 && e.LastRenderedY < height && e.LastRenderedY + e.LastRenderedHeight > 0
+}
 
-		err = renderer.SetRenderTarget(elementTexture)
-		if err != nil {
-			return nil, err
-		}
+func (e *Element) paint(renderer *sdl.Renderer, width, height int32) (*sdl.Texture, error) {
+	var contentTexture *sdl.Texture
 
-		renderer.SetDrawColor(e.BackgroundColor.R, e.BackgroundColor.G, e.BackgroundColor.B, e.BackgroundColor.A)
-		err = renderer.Clear()
-		if err != nil {
-			return nil, err
-		}
+	if e.Content != nil {
+		var err error
 
-		err = renderer.Copy(contentTexture, &sdl.Rect{0, 0, contentWidth, contentHeight}, &sdl.Rect{0, 0, contentWidth, contentHeight})
+		contentTexture, err = e.Content.Render(renderer)
 		if err != nil {
 			return nil, err
 		}
 
-		contentTexture.Destroy()
-
-		if e.Selected {
-			drawSelectionBorder(renderer, &sdl.Rect{0, 0, textureWidth, textureHeight})
-		}
+		defer contentTexture.Destroy()
+	}
 
-		e.LastRenderedWidth = textureWidth
-		e.LastRenderedHeight = textureHeight
+	var children []*Element
 
-		return elementTexture, nil
-	} else {
-		maxWidth := realWidth
+	glyphs := map[*Element]*Glyph{}
 
-		var currentX, currentY int32
+	err := e.renderPopups(renderer)
+	if err != nil {
+		return nil, err
+	}
 
-		var currentLineHeight int32
+	for _, child := range(e.Children) {
+		if !child.intersects(width, height) {
+			continue
+		}
 
-		var childWidth int32
+		children = append(children, child)
 
-		for i, child := range(e.Children) {
-			texture, err := child.Render(renderer)
+		if e.TextRun && child.IsGlyph() {
+			glyph, err := child.Content.(*Text).Glyph(renderer)
 			if err != nil {
 				return nil, err
 			}
 
-			child.RenderingTexture = texture
+			glyphs[child] = glyph
 
-			_, _, width, height, err := texture.Query()
-			if err != nil {
-				return nil, err
-			}
+			continue
+		}
 
-			child.LastRenderedMarginX = child.MarginX
-			child.LastRenderedMarginY = child.MarginY
+		if child.Painter != nil {
+			continue
+		}
 
-			if child.MarginXPercent {
-				child.LastRenderedMarginX = child.MarginX * realWidth / 100
-			}
-			if child.MarginYPercent {
-				child.LastRenderedMarginY = child.MarginY * realHeight / 100
-			}
+		texture, err := child.Render(renderer)
+		if err != nil {
+			return nil, err
+		}
 
-			totalHeight := height + child.LastRenderedMarginY
+		child.RenderingTexture = texture
+	}
 
-			newX := currentX + width + child.LastRenderedMarginX
+	// The texture has the pixel size of the element, and everything in it is
+	// painted in logical units through the scale of the renderer, which
+	// changing the target resets.
+	elementTexture, err := renderer.CreateTexture(sdl.PIXELFORMAT_RGBA8888, sdl.TEXTUREACCESS_TARGET, max(toPixels(width), 1), max(toPixels(height), 1))
+	if err != nil {
+		return nil, err
+	}
 
-			if !child.Inline && (newX > maxWidth && !e.ScrollX) || (i > 0 && e.Children[i - 1].Breaking) {
-				if currentX > childWidth {
-					childWidth = currentX
-				}
+	err = setElementBlendMode(elementTexture)
+	if err != nil {
+		return nil, err
+	}
 
-				currentX = 0
-				newX = currentX + width
+	err = renderer.SetRenderTarget(elementTexture)
+	if err != nil {
+		return nil, err
+	}
 
-				currentY += currentLineHeight
-				currentLineHeight = 0
-			}
+	err = renderer.SetScale(float32(uiScale), float32(uiScale))
+	if err != nil {
+		return nil, err
+	}
 
-			if totalHeight > currentLineHeight {
-				currentLineHeight = totalHeight
-			}
+	err = e.paintBox(renderer, width, height)
+	if err != nil {
+		return nil, err
+	}
 
-			child.LastRenderedX = currentX + child.LastRenderedMarginX - e.ScrollPositionX
-			child.LastRenderedY = currentY + child.LastRenderedMarginY - e.ScrollPositionY
+	top, right, _, left := e.insets()
 
-			currentX = newX
-		}
+	border := e.BorderWidth
 
-		if currentX > childWidth {
-			childWidth = currentX
-		}
+	renderer.SetClipRect(&sdl.Rect{border, border, width - 2 * border, height - 2 * border})
 
-		e.LastRenderedChildWidth = childWidth;
+	if contentTexture != nil {
+		contentWidth, contentHeight := e.Content.Measure()
 
-		currentY += currentLineHeight
+		err = renderer.Copy(contentTexture, nil, &sdl.Rect{left, top, contentWidth, contentHeight})
+		if err != nil {
+			return nil, err
+		}
+	}
 
-		e.LastRenderedChildHeight = currentY
+	var glyphChildren []*Element
 
-		if e.Height >= 0 {
-			currentY = realHeight
+	for _, child := range(children) {
+		if child.Overlay || child.alpha() == 0 {
+			continue
 		}
 
-		if e.Width >= 0 {
-			maxWidth = realWidth
-		}
+		child.paintShadow(renderer)
 
-		if maxWidth < e.MinWidth {
-			maxWidth = e.MinWidth
-		}
+		dimensions := &sdl.Rect{child.LastRenderedX, child.LastRenderedY, child.LastRenderedWidth, child.LastRenderedHeight}
+
+		if _, ok := glyphs[child]; ok {
+			if child.BackgroundColor.A != 0 {
+				renderer.SetDrawColor(child.BackgroundColor.R, child.BackgroundColor.G, child.BackgroundColor.B, child.BackgroundColor.A)
+				renderer.FillRect(dimensions)
+			}
 
-		if currentY < e.MinHeight {
-			currentY = e.MinHeight
+			glyphChildren = append(glyphChildren, child)
+
+			continue
 		}
 
-		elementTexture, err := renderer.CreateTexture(sdl.PIXELFORMAT_RGB24, sdl.TEXTUREACCESS_TARGET, maxWidth, currentY)
+		err = setElementOpacity(child.RenderingTexture, child.alpha())
 		if err != nil {
 			return nil, err
 		}
 
-		err = renderer.SetRenderTarget(elementTexture)
+		err = renderer.Copy(child.RenderingTexture, nil, dimensions)
 		if err != nil {
 			return nil, err
 		}
+	}
 
-		renderer.SetDrawColor(e.BackgroundColor.R, e.BackgroundColor.G, e.BackgroundColor.B, e.BackgroundColor.A)
-		err = renderer.Clear()
+	if len(glyphChildren) > 0 {
+		err = paintGlyphs(renderer, glyphChildren, glyphs, &sdl.Rect{border, border, width - 2 * border, height - 2 * border})
 		if err != nil {
 			return nil, err
 		}
+	}
 
-		for _, child := range(e.Children) {
-			_, _, width, height, err := child.RenderingTexture.Query()
-			if err != nil {
-				return nil, err
-			}
-
-			if !e.ScrollX {
-				e.ScrollPositionX = 0
-			}
-			if !e.ScrollY {
-				e.ScrollPositionY = 0
-			}
-
-			err = renderer.Copy(child.RenderingTexture, nil, &sdl.Rect{child.LastRenderedX, child.LastRenderedY, width, height})
-			if err != nil {
-				return nil, err
-			}
-
-			child.RenderingTexture.Destroy()
+	for _, child := range(children) {
+		if !child.Overlay {
+			continue
 		}
 
-		if e.Selected {
-			drawSelectionBorder(renderer, &sdl.Rect{0, 0, maxWidth, currentY})
+		err = child.paintOverlay(renderer)
+		if err != nil {
+			return nil, err
 		}
-
-		e.LastRenderedWidth = maxWidth
-		e.LastRenderedHeight = currentY
-
-		return elementTexture, nil
 	}
 
-	return nil, nil
-}
-
-func (e *Element) AddEventHandler(handler func(Event)) {
-	e.EventHandlers = append(e.EventHandlers, handler)
-}
+	indicatorX := width - right - 3
+	if e.overflows(true) {
+		indicatorX -= scrollbarWidth
+	}
 
-func (e *Element) Emit(event Event) {
-	for _, handler := range(e.EventHandlers) {
-		handler(event)
+	for _, wrap := range(e.Wraps) {
+		drawWrapIndicator(renderer, &sdl.Rect{indicatorX, wrap.Y - e.ScrollPositionY, 3, wrap.H})
 	}
-}
 
-func inBox(x, y, boxX, boxY, boxW, boxH int32) bool {
-	return x >= boxX && x < boxX + boxW && y >= boxY && y < boxY + boxH
-}
+	e.paintScrollbars(renderer)
 
-func (e *Element) MouseUpdate(root *Element, selected **Element, x, y int32, oldMouseButtonStates [3]bool, newMouseButtonStates [3]bool, overMe bool) {
-	if overMe && !e.MouseHovering {
-		e.MouseHovering = true
+	renderer.SetClipRect(nil)
 
-		e.Emit(MouseHoverEvent(true))
+	if e.Selected && !e.HideFocusRing {
+		drawFocusRing(renderer, &sdl.Rect{0, 0, width, height}, e.CornerRadius)
 	}
 
-	if !overMe && e.MouseHovering {
-		e.MouseHovering = false
-
-		e.Emit(MouseHoverEvent(false))
+	err = e.paintPopups(renderer)
+	if err != nil {
+		return nil, err
 	}
 
-	for b, newState := range(newMouseButtonStates) {
-		oldState := oldMouseButtonStates[b]
+	return elementTexture, nil
+}
 
-		if !oldState && newState && overMe {
-			e.MouseButtonClicks[b] = true
+// paintGlyphs copies the glyphs of elements at the pixel positions of the
+// elements without the scale of the renderer, so that each glyph lands on
+// the display exactly as the font rasterized it. clip is in logical units.
+func paintGlyphs(renderer *sdl.Renderer, elements []*Element, glyphs map[*Element]*Glyph, clip *sdl.Rect) error {
+	err := renderer.SetScale(1, 1)
+	if err != nil {
+		return err
+	}
 
-			e.Emit(MouseButtonEvent{MouseButtonEventDown, b})
+	renderer.SetClipRect(&sdl.Rect{toPixels(clip.X), toPixels(clip.Y), toPixels(clip.W), toPixels(clip.H)})
 
-			if e.Selectable {
-				*selected = e
-			}
-		}
+	for _, element := range(elements) {
+		glyph := glyphs[element]
 
-		if !overMe {
-			e.MouseButtonClicks[b] = false
+		// Glyphs come straight from the font with straight alpha.
+		err = glyph.Texture.SetAlphaMod(element.alpha())
+		if err != nil {
+			return err
 		}
 
-		if oldState && !newState && overMe {
-			e.Emit(MouseButtonEvent{MouseButtonEventUp, b})
-
-			if e.MouseButtonClicks[b] {
-				e.Emit(MouseButtonEvent{MouseButtonEventClick, b})
-			}
+		err = renderer.Copy(glyph.Texture, nil, &sdl.Rect{toPixels(element.LastRenderedX), toPixels(element.LastRenderedY), glyph.PixelWidth, glyph.PixelHeight})
+		if err != nil {
+			return err
 		}
+	}
 
-		if !newState {
-			e.MouseButtonClicks[b] = false
-		}
+	err = renderer.SetScale(float32(uiScale), float32(uiScale))
+	if err != nil {
+		return err
 	}
 
-	for _, child := range(e.Children) {
-		overChild := overMe && inBox(x, y, child.LastRenderedX, child.LastRenderedY, child.LastRenderedWidth, child.LastRenderedHeight)
+	renderer.SetClipRect(clip)
 
-		child.MouseUpdate(root, selected, x - child.LastRenderedX, y - child.LastRenderedY, oldMouseButtonStates, newMouseButtonStates, overChild)
-	}
+	return nil
 }
 
-func (e *Element) Scroll(mouseX, mouseY, scrollX, scrollY int32) bool {
-	if e.Content != nil {
-		return false;
-	}
+func (e *Element) paintOverlay(renderer *sdl.Renderer) error {
+	dimensions := &sdl.Rect{e.LastRenderedX, e.LastRenderedY, e.LastRenderedWidth, e.LastRenderedHeight}
 
-	for _, child := range(e.Children) {
-		if child.Scroll(mouseX, mouseY, scrollX, scrollY) {
-			return true;
-		}
-	}
+	if e.Painter != nil {
+		e.Painter(renderer, dimensions)
 
-	if !inBox(mouseX, mouseY, e.LastRenderedX, e.LastRenderedY, e.LastRenderedWidth, e.LastRenderedHeight) {
-		return false;
+		return nil
 	}
 
-	scrolled := false
-
-	if !e.ScrollY || e.LastRenderedHeight >= e.LastRenderedChildHeight {
-		scrollX = scrollY
+	if e.alpha() == 0 {
+		return nil
 	}
 
-	for i := 0; i < 3; i++ {
-		if e.ScrollX {
-			if e.ScrollPositionX + e.LastRenderedWidth >= e.LastRenderedChildWidth {
-				if e.LastRenderedChildWidth >= e.LastRenderedWidth {
-					e.ScrollPositionX = e.LastRenderedChildWidth - e.LastRenderedWidth;
-				} else {
-					e.ScrollPositionX = 0
-				}
-			} else if e.ScrollPositionX <= 0 {
-				e.ScrollPositionX = 0
-			} else if i == 0 {
-				scrolled = true
-
-				e.ScrollPositionX -= scrollX * 60
-			}
-		}
-
-		if e.ScrollY {
-			if e.ScrollPositionY + e.LastRenderedHeight > e.LastRenderedChildHeight {
-				if e.LastRenderedChildHeight >= e.LastRenderedHeight {
-					e.ScrollPositionY = e.LastRenderedChildHeight - e.LastRenderedHeight;
-				} else {
-					e.ScrollPositionY = 0
-				}
-			} else if e.ScrollPositionY < 0 {
-				e.ScrollPositionY = 0
-			} else if i == 0 {
-				scrolled = true
-
-				e.ScrollPositionY -= scrollY * 60
-			}
-		}
+	err := setElementOpacity(e.RenderingTexture, e.alpha())
+	if err != nil {
+		return err
 	}
 
-	return scrolled
+	return renderer.Copy(e.RenderingTexture, nil, dimensions)
+}
+
+func inBox(x, y, boxX, boxY, boxW, boxH int32) bool {
+	return x >= boxX && x < boxX + boxW && y >= boxY && y < boxY + boxH
 }
 
 func (e *Element) AppendChild(child *Element) {
 	e.Content = nil
 
 	child.Parent = e
+	child.LaidOut = false
 
 	e.Children = append(e.Children, child)
+
+	e.Invalidate()
 }
 
 func (e *Element) InsertChild(child *Element, i int) {
@@ -563,8 +660,11 @@ func (e *Element) InsertChild(child *Element, i int) {
 	e.Content = nil
 
 	child.Parent = e
+	child.LaidOut = false
 
 	e.Children = append(e.Children[:i], append([]*Element{child}, e.Children[i:]...)...)
+
+	e.Invalidate()
 }
 
 func (e *Element) SetContent(content ElementContent) {
@@ -575,6 +675,8 @@ func (e *Element) SetContent(content ElementContent) {
 	if content != nil {
 		content.SetContainer(e)
 	}
+
+	e.Invalidate()
 }
 
 func (e *Element) Locate() (int32, int32) {
@@ -587,12 +689,38 @@ func (e *Element) Locate() (int32, int32) {
 	return 0, 0
 }
 
+func (e *Element) Index() int {
+	if e.Parent == nil {
+		return -1
+	}
+
+	for i, child := range(e.Parent.Children) {
+		if child == e {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (e *Element) SetSelected(selected bool) {
+	if e.Selected != selected {
+		e.Selected = selected
+
+		e.Invalidate()
+	}
+}
+
 func (e *Element) DeselectAll() {
-	e.Selected = false
+	e.SetSelected(false)
 
 	for _, child := range(e.Children) {
 		child.DeselectAll()
 	}
+
+	for _, popup := range(e.Popups) {
+		popup.DeselectAll()
+	}
 }
 
 func (e *Element) Remove() {
@@ -600,6 +728,11 @@ func (e *Element) Remove() {
 		return
 	}
 
+	if e.IsPopup() {
+		e.Parent.ClosePopup(e)
+		return
+	}
+
 	i := 0
 
 	for ; i < len(e.Parent.Children); i++ {
@@ -609,8 +742,12 @@ func (e *Element) Remove() {
 	}
 
 	if i < len(e.Parent.Children) {
+		e.Parent.Invalidate()
+
 		e.Parent.Children = append(e.Parent.Children[:i], e.Parent.Children[i + 1:]...)
 		e.Parent = nil
+
+		e.ReleaseTextures()
 	}
 }
 
@@ -639,35 +776,16 @@ func loadImage(file string) (*Image, error) {
 	return image, nil
 }
 
-func newCharElement(font *ttf.Font, c byte, textEditingArea *Element, cursor *Cursor) *Element {
+func newCharElement(editor *Editor, c byte) *Element {
 	charElement := &Element{
 		Width: -1,
 		Height: -1,
 	}
 
-	charElement.AddEventHandler(func(event Event) {
-		switch e := event.(type) {
-		case MouseButtonEvent:
-			if e.Type == MouseButtonEventDown && e.Button == 0 {
-				for y, row := range(textEditingArea.Children) {
-					for x, char := range(row.Children) {
-						if char == charElement {
-							cursor.CursorElement.Remove()
-							cursor.X = x + 1
-							cursor.Y = y
-							row.InsertChild(cursor.CursorElement, cursor.X)
-							break
-						}
-					}
-				}
-			}
-		}
-	})
-
 	text := &Text{
 		Content: string(c),
-		Font: font,
-		Color: sdl.Color{255, 255, 255, 255},
+		Font: editor.Font,
+		Color: editorTextColor,
 	}
 
 	charElement.SetContent(text)
@@ -675,96 +793,99 @@ func newCharElement(font *ttf.Font, c byte, textEditingArea *Element, cursor *Cu
 	return charElement
 }
 
-func newLineElement(textEditingArea *Element, cursor *Cursor) *Element {
+func newLineElement(editor *Editor) *Element {
 	lineElement := &Element{
-		Width: 100,
-		WidthPercent: true,
 		Height: -1,
 		MinHeight: 40,
+
+		Breaking: true,
+		TextRun: true,
 	}
 
-	lineElement.AddEventHandler(func(event Event) {
-		switch e := event.(type) {
-		case MouseButtonEvent:
-			if e.Type == MouseButtonEventDown && e.Button == 0 {
-				for y, row := range(textEditingArea.Children) {
-					if row == lineElement {
-						cursor.CursorElement.Remove()
-						cursor.X = len(lineElement.Children)
-						cursor.Y = y
-						row.InsertChild(cursor.CursorElement, cursor.X)
-						break
-					}
-				}
-			}
-		}
-	})
+	editor.styleLine(lineElement)
 
 	return lineElement
 }
 
-func writeChar(textEditingArea *Element, font *ttf.Font, cursor *Cursor, c byte) {
+func writeChar(editor *Editor, cursor *Cursor, c byte) {
+	textEditingArea := editor.TextArea
+
+	row := cursor.CursorElement.Parent
+
 	switch c {
 	case '\b':
 		if cursor.X == 0 {
 			if cursor.Y != 0 {
-				cursor.CursorElement.Remove()
-
-				lastRow := textEditingArea.Children[cursor.Y]
-
-				cursor.Y--
+				lastRow := row
 
-				removed := 0
+				row = textEditingArea.Children[cursor.Y - 1]
 
 				for len(lastRow.Children) > 0 {
 					child := lastRow.Children[0]
 					child.Remove()
-					textEditingArea.Children[cursor.Y].AppendChild(child)
-					removed++
+					row.AppendChild(child)
 				}
 
 				lastRow.Remove()
-				cursor.X = len(textEditingArea.Children[cursor.Y].Children) - removed
-				textEditingArea.Children[cursor.Y].InsertChild(cursor.CursorElement, cursor.X)
 			}
 		} else {
-			textEditingArea.Children[cursor.Y].Children[cursor.X - 1].Remove()
-			cursor.X--
+			rowChars(row)[cursor.X - 1].Remove()
 		}
 	case '\x7F':
-		if cursor.X + 1 < len(textEditingArea.Children[cursor.Y].Children) {
-			textEditingArea.Children[cursor.Y].Children[cursor.X + 1].Remove()
+		chars := rowChars(row)
+
+		if cursor.X < len(chars) {
+			chars[cursor.X].Remove()
 		} else if cursor.Y + 1 < len(textEditingArea.Children) {
 			nextRow := textEditingArea.Children[cursor.Y + 1]
 
 			for len(nextRow.Children) > 0 {
 				child := nextRow.Children[0]
 				child.Remove()
-				textEditingArea.Children[cursor.Y].AppendChild(child)
+				row.AppendChild(child)
 			}
 
 			nextRow.Remove()
 		}
 	case '\n':
-		line := newLineElement(textEditingArea, cursor)
+		line := newLineElement(editor)
 		textEditingArea.InsertChild(line, cursor.Y + 1)
 
-		for len(textEditingArea.Children[cursor.Y].Children) > cursor.X {
-			child := textEditingArea.Children[cursor.Y].Children[cursor.X]
+		i := cursor.CursorElement.Index()
+
+		for len(row.Children) > i {
+			child := row.Children[i]
 			child.Remove()
 			line.AppendChild(child)
 		}
-
-		cursor.CursorElement.Remove()
-		line.InsertChild(cursor.CursorElement, 0)
-		cursor.Y++
-		cursor.X = 0
 	default:
-		textEditingArea.Children[cursor.Y].InsertChild(newCharElement(font, c, textEditingArea, cursor), cursor.X)
-		cursor.X++
+		row.InsertChild(newCharElement(editor, c), cursor.CursorElement.Index())
 	}
+
+	syncCursor(editor, cursor)
+}
+
+func waitTimeout(dirty bool, nextFrame time.Time) int {
+	deadline := time.Now().Add(time.Second)
+
+	if dirty {
+		deadline = nextFrame
+	}
+
+	if next, ok := nextTimerDeadline(); ok && next.Before(deadline) {
+		deadline = next
+	}
+
+	return max(int(time.Until(deadline) / time.Millisecond), 0)
 }
 
+const (
+	fontPath = "./fonts/Xanh_Mono/XanhMono-Regular.ttf"
+
+	// fontSize is the size of the font in logical units.
+	fontSize = 30
+)
+
 func main() {
 	err := sdl.Init(sdl.INIT_EVERYTHING)
 	if err != nil {
@@ -781,42 +902,165 @@ func main() {
 		panic(err)
 	}
 
-	if len(os.Args) != 2 {
-		sdl.ShowMessageBox(&sdl.MessageBoxData{
-			Flags: sdl.MESSAGEBOX_ERROR,
-			Title: "AshKmodify Error",
-			Message: "AshKmodify must be executed with exactly one command line argument specifying the file that it is to edit",
-			Buttons: []sdl.MessageBoxButtonData{
-				{
-					Flags: sdl.MESSAGEBOX_BUTTON_RETURNKEY_DEFAULT,
-					Text: "Bother",
-				},
-			},
-		})
+	maxFPS := flag.Int("fps", 60, "the maximum number of frames to draw per second")
+	cursorStyle := flag.String("cursor-style", "bar", "the shape of the cursor: bar, block or underline")
+	cursorBlink := flag.Int("cursor-blink", 530, "the number of milliseconds between cursor blinks, or 0 to disable blinking")
+	wrapColumn := flag.Int("wrap-column", 0, "the column to wrap lines at, or 0 to wrap them at the width of the window")
+	flag.IntVar(&wheelScrollLines, "scroll-lines", wheelScrollLines, "the number of lines to scroll per notch of the mouse wheel")
+	scrollMarginLines := flag.Int("scroll-margin-lines", 2, "the number of lines to keep between the cursor and the top or bottom of the text area")
+	scrollMarginColumns := flag.Int("scroll-margin-columns", 4, "the number of columns to keep between the cursor and the sides of the text area")
+	scale := flag.Float64("scale", 0, "the size of the interface relative to 96 DPI, or 0 to follow the display")
+	flag.BoolVar(&smoothScrolling, "smooth-scroll", smoothScrolling, "animate scrolling with the mouse wheel and scrollbars")
+	themeName := flag.String("theme", "dark", "the color theme: dark, light, high-contrast, system to ask the system for its dark mode setting, or the path of a theme file")
+	keepSession := flag.Bool("session", false, "remember the open file and its unsaved changes, to be restored by the next run without arguments")
+	restore := flag.Bool("restore", false, "reopen the file given, or the last one, with the unsaved changes the session kept")
+
+	flag.Parse()
+
+	// Without a file, the files of the session are restored if there are
+	// any, the one last open in this run. With --restore, the file given is
+	// reopened with the unsaved changes the session kept for it.
+	var session *Session
+	var sessionFile *SessionFile
+
+	if flag.NArg() == 0 || *restore {
+		session, err = LoadSession()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
 
-		return
+	if session != nil {
+		if flag.NArg() == 0 {
+			sessionFile = session.Files[0]
+		} else {
+			sessionFile = session.File(flag.Arg(0))
+		}
+	}
+
+	// The other files of the session are reopened by runs of their own.
+	if flag.NArg() == 0 && sessionFile != nil {
+		err = ReopenFiles(session.Files[1:])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
 	}
 
-	filePath := os.Args[1]
+	// Wrong arguments are reported in a dialog once the window is open, and
+	// no file is opened.
+	usageError := flag.NArg() > 1 || (flag.NArg() == 0 && sessionFile == nil)
+
+	filePath := flag.Arg(0)
+	if sessionFile != nil {
+		filePath = sessionFile.Path
+	} else if usageError {
+		filePath = ""
+	}
+
+	// A theme file joins the built-in themes when switching between them.
+	var customTheme *Theme
+
+	theme := builtinTheme(*themeName)
 
-	data, err := os.ReadFile(filePath)
-	if err != nil && !errors.Is(err, os.ErrNotExist) {
+	if *themeName == "system" {
+		theme = systemTheme()
+	} else if theme == nil {
+		customTheme, err = LoadTheme(*themeName)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+
+		theme = customTheme
+	}
+
+	if theme == nil {
+		theme = darkTheme
+	}
+
+	theme.apply()
+
+	var data []byte
+
+	// Unsaved changes that cannot be read are reported once the window is
+	// open, and the file is opened as it was last saved instead.
+	var lostChanges bool
+
+	if sessionFile != nil {
+		data, err = sessionFile.Contents()
+
+		if err != nil && sessionFile.Unsaved {
+			fmt.Fprintln(os.Stderr, err)
+
+			lostChanges = true
+			sessionFile.Unsaved = false
+
+			data, err = sessionFile.Contents()
+		}
+	} else if !usageError {
+		data, err = os.ReadFile(filePath)
+	}
+
+	if !usageError && err != nil && !errors.Is(err, os.ErrNotExist) {
 		panic(err)
 	}
 
-	window, err := sdl.CreateWindow("AshKmodify: " + filePath, sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED, 1280, 720, sdl.WINDOW_OPENGL | sdl.WINDOW_RESIZABLE | sdl.WINDOW_SHOWN)
+	state, err := LoadState()
 	if err != nil {
-		panic(err)
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	// The window opens where it was left, as long as that is still on a
+	// display.
+	windowState := state.Window
+	restoreWindow := windowState != nil && windowState.Width > 0 && windowState.Height > 0
+
+	var windowX, windowY, windowW, windowH int32 = sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED, 1280, 720
+
+	if restoreWindow {
+		windowState.fitOnScreen()
+
+		windowX, windowY, windowW, windowH = windowState.X, windowState.Y, windowState.Width, windowState.Height
+	} else {
+		windowState = &WindowState{}
+		state.Window = windowState
+	}
+
+	title := "AshKmodify"
+	if !usageError {
+		title += ": " + filePath
 	}
 
-	font, err := ttf.OpenFont("./fonts/Xanh_Mono/XanhMono-Regular.ttf", 30)
+	window, err := sdl.CreateWindow(title, windowX, windowY, windowW, windowH, sdl.WINDOW_OPENGL | sdl.WINDOW_RESIZABLE | sdl.WINDOW_SHOWN | sdl.WINDOW_ALLOW_HIGHDPI)
 	if err != nil {
 		panic(err)
 	}
 
-	var selectedElement *Element
+	renderer, err := sdl.CreateRenderer(window, -1, sdl.RENDERER_ACCELERATED | sdl.RENDERER_PRESENTVSYNC | sdl.RENDERER_TARGETTEXTURE)
+	if err != nil {
+		renderer, err = sdl.CreateRenderer(window, -1, sdl.RENDERER_SOFTWARE)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	detectScale(window, renderer, *scale)
+
+	// The window was opened at 1280x720 window coordinates, which are only
+	// logical units if the system scales them itself.
+	if !restoreWindow && uiScale != pointScale {
+		window.SetSize(int32(1280 * uiScale / pointScale), int32(720 * uiScale / pointScale))
+		updatePointScale(window, renderer)
+	}
+
+	if restoreWindow && windowState.Maximized {
+		window.Maximize()
+	}
+
+	windowState.Track(window)
 
-	renderer, err := sdl.CreateRenderer(window, -1, sdl.RENDERER_SOFTWARE)
+	// The font is opened at its size in pixels so that glyphs are rasterized
+	// for the display, and measured back in logical units.
+	font, err := ttf.OpenFont(fontPath, int(toPixels(fontSize)))
 	if err != nil {
 		panic(err)
 	}
@@ -824,17 +1068,26 @@ func main() {
 	root := &Element{
 		Width: 1280,
 		Height: 720,
+
+		Layout: LayoutFlex,
+		Direction: DirectionColumn,
 	}
 
 	topBar := &Element{
-		Width: 100,
-		WidthPercent: true,
+		Width: -1,
 		Height: 40,
 
-		BackgroundColor: sdl.Color{32, 32, 32, 255},
+		Layout: LayoutFlex,
+		Align: AlignCenter,
+		Gap: 5,
+		PaddingLeft: 5,
 	}
 	root.AppendChild(topBar)
 
+	topBar.OnTheme(func() {
+		topBar.BackgroundColor = topBarColor
+	})
+
 	/*buttonNew := &Element{
 		Width: 30,
 		Height: 30,
@@ -869,185 +1122,219 @@ func main() {
 
 	topBar.AppendChild(buttonLoad)*/
 
-	buttonSave := &Element{
-		Width: 30,
-		Height: 30,
-
-		MarginX: 5,
-		MarginY: 5,
-	}
-
 	imgButtonSave, err := loadImage("images/save.png")
 	if err != nil {
 		panic(err)
 	}
 
-	buttonSave.SetContent(imgButtonSave)
+	buttonSave := NewImageButton(imgButtonSave, nil)
 
-	topBar.AppendChild(buttonSave)
+	topBar.AppendChild(buttonSave.Element)
 
 	textEditingArea := &Element{
-		Width: 100,
-		WidthPercent: true,
-		Height: 100,
+		Width: -1,
+		Height: -1,
+		Grow: 1,
 
 		ScrollY: true,
 
+		Virtualize: true,
+
 		Selectable: true,
 		Selected: true,
 
 		IsTextInput: true,
+	}
 
-		BackgroundColor: sdl.Color{64, 64, 64, 255},
-	}
-
-	cursor := NewCursor()
-
-	firstLine := newLineElement(textEditingArea, cursor)
-
-	textEditingArea.AppendChild(firstLine)
-
-	firstLine.AppendChild(cursor.CursorElement)
-
-	textEditingArea.AddEventHandler(func(event Event) {
-		switch e := event.(type) {
-		case TextEvent:
-			writeChar(textEditingArea, font, cursor, byte(e))
-		case KeyEvent:
-			if e.Type == sdl.KEYDOWN {
-				switch e.Code {
-				case sdl.K_RETURN:
-					writeChar(textEditingArea, font, cursor, '\n')
-				case sdl.K_BACKSPACE:
-					writeChar(textEditingArea, font, cursor, '\b')
-				case sdl.K_DELETE:
-					writeChar(textEditingArea, font, cursor, '\x7F')
-				case sdl.K_RIGHT, sdl.K_LEFT, sdl.K_DOWN, sdl.K_UP:
-					cursor.CursorElement.Remove()
-
-					switch e.Code {
-					case sdl.K_RIGHT:
-						if cursor.X < len(textEditingArea.Children[cursor.Y].Children) {
-							cursor.X++
-						} else if cursor.Y + 1 < len(textEditingArea.Children) {
-							cursor.Y++
-							cursor.X = 0
-						}
-					case sdl.K_LEFT:
-						if cursor.X > 0 {
-							cursor.X--
-						} else if cursor.Y > 0 {
-							cursor.Y--
-							cursor.X = len(textEditingArea.Children[cursor.Y].Children)
-						}
-					case sdl.K_UP:
-						if cursor.Y > 0 {
-							cursor.Y--
-							cursor.X = min(cursor.X, len(textEditingArea.Children[cursor.Y].Children))
-						}
-					case sdl.K_DOWN:
-						if cursor.Y + 1 < len(textEditingArea.Children) {
-							cursor.Y++
-							cursor.X = min(cursor.X, len(textEditingArea.Children[cursor.Y].Children))
-						}
-					}
-
-					textEditingArea.Children[cursor.Y].InsertChild(cursor.CursorElement, cursor.X)
-				}
-			}
-		}
+	textEditingArea.OnTheme(func() {
+		textEditingArea.BackgroundColor = editorColor
 	})
 
+	editor := NewEditor(textEditingArea, font)
+
+	editor.CursorStyle = parseCursorStyle(*cursorStyle)
+	editor.SetBlinkRate(time.Duration(*cursorBlink) * time.Millisecond)
+	editor.SetSoftWrap(editor.SoftWrap, *wrapColumn)
+	editor.ScrollMarginLines = max(*scrollMarginLines, 0)
+	editor.ScrollMarginColumns = max(*scrollMarginColumns, 0)
+
+	textEditingArea.AddEventHandler(editor.HandleEvent)
+
 	root.AppendChild(textEditingArea)
 
-	buttonSave.AddEventHandler(func(event Event) {
-		switch e := event.(type) {
-		case MouseButtonEvent:
-			if e.Type == MouseButtonEventClick && e.Button == 0 {
-				file, err := os.Create(filePath)
-				defer file.Close()
-
-				if err != nil {
-					sdl.ShowMessageBox(&sdl.MessageBoxData{
-						Flags: sdl.MESSAGEBOX_ERROR,
-						Title: "AshKmodify Error",
-						Message: "The file cannot be opened for writing",
-						Buttons: []sdl.MessageBoxButtonData{
-							{
-								Flags: sdl.MESSAGEBOX_BUTTON_RETURNKEY_DEFAULT,
-								Text: "Bother",
-							},
-						},
-					})
+	// saveFile writes the text to the file, and reports whether it could.
+	saveFile := func() bool {
+		file, err := os.Create(filePath)
+		defer file.Close()
+
+		if err != nil {
+			ShowMessage(root, font, "AshKmodify Error", "The file cannot be opened for writing", "Bother")
+
+			return false
+		}
+
+		_, err = file.WriteString(editor.Text())
+		if err != nil {
+			ShowMessage(root, font, "AshKmodify Error", "There was an error while writing to the file", "Bother")
+
+			return false
+		}
+
+		return true
+	}
+
+	buttonSave.OnClick = func() {
+		if saveFile() {
+			ShowMessage(root, font, "AshKmodify", "File saved successfully", "Fantastic")
+		}
+	}
 
+	focus := NewFocusManager(root)
+	focus.Focus(textEditingArea)
+
+	for _, c := range(data) {
+		writeChar(editor, editor.PrimaryCursor(), c)
+	}
+
+	position := state.FilePosition(filePath)
+	if sessionFile != nil {
+		position = sessionFile.Position
+	}
+
+	if position != nil {
+		position.Restore(editor)
+	}
+
+	if lostChanges {
+		ShowMessage(root, font, "AshKmodify Error", "The unsaved changes from the last session cannot be read,\nso the file is opened as it was last saved", "Bother")
+	} else if sessionFile != nil && sessionFile.Unsaved && sessionFile.FileChanged() {
+		ShowDialog(root, font, DialogOptions{
+			Title: "AshKmodify",
+			Message: "The file has changed since the session was saved.\nKeep the unsaved changes from the session over it?",
+			Buttons: []string{"Open the file", "Keep my changes"},
+			DefaultButton: 1,
+			CancelButton: 1,
+
+			OnClose: func(result DialogResult) {
+				if result.Button != 0 {
 					return
 				}
 
-				for i, row := range(textEditingArea.Children) {
-					for _, c := range(row.Children) {
-						if c.Content == nil {
-							continue
-						}
-
-						_, err := file.WriteString(c.Content.(*Text).Content)
-						if err != nil {
-							goto bother
-						}
-					}
+				data, err := os.ReadFile(filePath)
+				if err != nil && !errors.Is(err, os.ErrNotExist) {
+					ShowMessage(root, font, "AshKmodify Error", "The file cannot be read", "Bother")
 
-					if i + 1 < len(textEditingArea.Children) {
-						_, err := file.WriteString("\n")
-						if err != nil {
-							goto bother
-						}
-					}
+					return
 				}
 
-				sdl.ShowMessageBox(&sdl.MessageBoxData{
-					Flags: sdl.MESSAGEBOX_INFORMATION,
-					Title: "AshKmodify",
-					Message: "File saved successfully",
-					Buttons: []sdl.MessageBoxButtonData{
-						{
-							Flags: sdl.MESSAGEBOX_BUTTON_RETURNKEY_DEFAULT,
-							Text: "Fantastic",
-						},
-					},
-				})
-
-				return
-
-				bother:
-				sdl.ShowMessageBox(&sdl.MessageBoxData{
-					Flags: sdl.MESSAGEBOX_ERROR,
-					Title: "AshKmodify Error",
-					Message: "There was an error while writing to the file",
-					Buttons: []sdl.MessageBoxButtonData{
-						{
-							Flags: sdl.MESSAGEBOX_BUTTON_RETURNKEY_DEFAULT,
-							Text: "Bother",
-						},
-					},
-				})
+				editor.SetText(data)
+			},
+		})
+	}
 
-			}
+	// A restored session stays on, so that it is kept up to date. It is also
+	// written a moment after the last edit, so that a crash loses little.
+	sessionOn := (*keepSession || session != nil) && !usageError
+
+	saveSession := func() {
+		err := SaveSession(filePath, editor)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
 		}
-	})
+	}
 
-	selectedElement = textEditingArea
+	if sessionOn {
+		sessionTimer := AddTimer(sessionSaveDelay, false, saveSession)
+		sessionTimer.Stop()
 
-	for _, c := range(data) {
-		writeChar(textEditingArea, font, cursor, c)
+		textEditingArea.AddEventHandler(func(event Event) {
+			switch event.(type) {
+			case *TextEvent, *KeyEvent:
+				sessionTimer.Reset()
+			}
+		})
 	}
 
-	var oldMouseButtonStates [3]bool
+	pointer := NewPointer(root)
+
+	frameInterval := time.Second / time.Duration(max(*maxFPS, 1))
+	var lastFrame time.Time
 
 	running := true
+
+	if usageError {
+		ShowDialog(root, font, DialogOptions{
+			Title: "AshKmodify Error",
+			Message: "AshKmodify must be executed with exactly one file argument specifying\nthe file that it is to edit, or with none to restore the last session",
+			Buttons: []string{"Bother"},
+
+			OnClose: func(DialogResult) {
+				running = false
+			},
+		})
+	}
+
+	// Quitting with changes that are not saved asks first, unless the
+	// session keeps them.
+	var quitDialog *Dialog
+
+	quit := func() {
+		if quitDialog != nil {
+			return
+		}
+
+		if usageError || sessionOn {
+			running = false
+			return
+		}
+
+		// A file that cannot be read to compare with counts as changed, so
+		// that nothing is thrown away without asking.
+		unsaved, err := unsavedChanges(filePath, editor.Text())
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+
+			unsaved = true
+		}
+
+		if !unsaved {
+			running = false
+			return
+		}
+
+		quitDialog = ShowDialog(root, font, DialogOptions{
+			Title: "AshKmodify",
+			Message: "The file has changes that have not been saved.\nSave them before quitting?",
+			Buttons: []string{"Save", "Quit without saving", "Cancel"},
+			CancelButton: 2,
+
+			OnClose: func(result DialogResult) {
+				quitDialog = nil
+
+				switch result.Button {
+				case 0:
+					running = !saveFile()
+				case 1:
+					running = false
+				}
+			},
+		})
+	}
+
+	inputTarget := func() *Element {
+		focus.Update()
+
+		return focus.Focused
+	}
+
 	for running {
-		if selectedElement != nil && selectedElement.IsTextInput {
-			inputElementX, inputElementY := selectedElement.Locate()
+		inputElement := inputTarget()
+
+		if inputElement != nil && inputElement.IsTextInput {
+			inputElementX, inputElementY := inputElement.Locate()
+			inputRect := logicalToWindow(sdl.Rect{inputElementX, inputElementY, inputElement.LastRenderedWidth, inputElement.LastRenderedHeight})
 
-			sdl.SetTextInputRect(&sdl.Rect{inputElementX, inputElementY, selectedElement.LastRenderedWidth, selectedElement.LastRenderedHeight})
+			sdl.SetTextInputRect(&inputRect)
 
 			if !sdl.IsTextInputActive() {
 				sdl.StartTextInput()
@@ -1056,51 +1343,120 @@ func main() {
 			sdl.StopTextInput()
 		}
 
-		for {
-			event := sdl.PollEvent()
-			if event == nil {
-				break
-			}
+		event := sdl.WaitEventTimeout(waitTimeout(root.Dirty, lastFrame.Add(frameInterval)))
 
+		for ; event != nil; event = sdl.PollEvent() {
 			switch e := event.(type) {
 			case *sdl.QuitEvent:
-				running = false
-				break
-			case *sdl.MouseMotionEvent, *sdl.MouseButtonEvent:
-				mouseX, mouseY, mouseState := sdl.GetMouseState()
-				buttonLeft, buttonMiddle, buttonRight := mouseState & 1 != 0, mouseState & 2 != 0, mouseState & 4 != 0
-				newMouseButtonStates := [3]bool{buttonLeft, buttonMiddle, buttonRight}
-				root.MouseUpdate(root, &selectedElement, mouseX, mouseY, oldMouseButtonStates, newMouseButtonStates, true)
-				oldMouseButtonStates = newMouseButtonStates
-
-				root.DeselectAll()
-
-				if selectedElement != nil && selectedElement.Selectable {
-					selectedElement.Selected = true
+				quit()
+			case *sdl.WindowEvent:
+				switch e.Event {
+				case sdl.WINDOWEVENT_FOCUS_GAINED:
+					editor.SetFocused(true)
+				case sdl.WINDOWEVENT_FOCUS_LOST:
+					editor.SetFocused(false)
+				case sdl.WINDOWEVENT_MOVED, sdl.WINDOWEVENT_SIZE_CHANGED, sdl.WINDOWEVENT_MAXIMIZED, sdl.WINDOWEVENT_RESTORED:
+					windowState.Track(window)
+				}
+
+				root.Invalidate()
+			case *sdl.MouseMotionEvent:
+				root.Arrange()
+
+				x, y := windowToLogical(e.X, e.Y)
+
+				pointer.Move(x, y, uint16(sdl.GetModState()))
+			case *sdl.MouseButtonEvent:
+				root.Arrange()
+
+				x, y := windowToLogical(e.X, e.Y)
+				button, mod := int(e.Button) - 1, uint16(sdl.GetModState())
+
+				if e.Type == sdl.MOUSEBUTTONDOWN {
+					if clicked := pointer.Press(button, x, y, int(e.Clicks), mod); clicked != nil {
+						focus.Focus(clicked)
+					}
+				} else {
+					pointer.Release(button, x, y, int(e.Clicks), mod)
 				}
 			case *sdl.MouseWheelEvent:
 				mouseX, mouseY, _ := sdl.GetMouseState()
+				mouseX, mouseY = windowToLogical(mouseX, mouseY)
 				scrollX, scrollY := e.X, e.Y
+
+				if e.Direction == sdl.MOUSEWHEEL_FLIPPED {
+					scrollX, scrollY = -scrollX, -scrollY
+				}
+
+				if sdl.GetModState() & sdl.KMOD_SHIFT != 0 {
+					scrollX, scrollY = -scrollY, 0
+				}
+
+				root.Arrange()
 				root.Scroll(mouseX, mouseY, scrollX, scrollY)
 			case *sdl.TextInputEvent:
-				if sdl.IsTextInputActive() {
-					selectedElement.Emit(TextEvent(e.Text[0]))
+				if target := inputTarget(); sdl.IsTextInputActive() && target != nil {
+					target.Dispatch(&TextEvent{Char: e.Text[0]})
 				}
 			case *sdl.KeyboardEvent:
-				if selectedElement != nil {
-					selectedElement.Emit(KeyEvent{e.Type, e.Keysym.Sym})
+				target := inputTarget()
+
+				if target != nil && !target.Dispatch(&KeyEvent{Type: e.Type, Code: e.Keysym.Sym, Mod: e.Keysym.Mod}) {
+					break
 				}
+
+				// The text area keeps Tab for indenting, so Ctrl+Tab moves focus
+				// as well.
+				if e.Type == sdl.KEYDOWN && e.Keysym.Sym == sdl.K_TAB {
+					if e.Keysym.Mod & sdl.KMOD_SHIFT != 0 {
+						focus.Previous()
+					} else {
+						focus.Next()
+					}
+				}
+
+				if e.Type == sdl.KEYDOWN && e.Keysym.Sym == sdl.K_t && e.Keysym.Mod & sdl.KMOD_CTRL != 0 && e.Keysym.Mod & sdl.KMOD_SHIFT != 0 {
+					SetTheme(root, NextTheme(customTheme))
+				}
+			}
+		}
+
+		// The window may have moved to a display with another density, which
+		// needs the font rasterized at another size.
+		if updateScale(window, renderer, *scale) {
+			err = reopenFont(font, fontPath, int(toPixels(fontSize)))
+			if err != nil {
+				panic(err)
 			}
+
+			clearTextCaches()
+
+			root.eachEditor((*Editor).resize)
+
+			root.InvalidateTree()
+		}
+
+		outputW, outputH, err := renderer.GetOutputSize()
+		if err != nil {
+			panic(err)
 		}
 
-		windowW, windowH := window.GetSize()
+		if width, height := toLogical(outputW), toLogical(outputH); root.Width != width || root.Height != height {
+			root.Width = width
+			root.Height = height
+
+			root.Invalidate()
+		}
 
-		root.Width = windowW
-		root.Height = windowH
+		runTimers(time.Now())
 
-		textEditingArea.Height = windowH - topBar.Height
+		if !root.Dirty || time.Since(lastFrame) < frameInterval {
+			continue
+		}
 
-		err := renderer.SetDrawBlendMode(sdl.BLENDMODE_BLEND)
+		lastFrame = time.Now()
+
+		err = renderer.SetDrawBlendMode(sdl.BLENDMODE_BLEND)
 		if err != nil {
 			panic(err)
 		}
@@ -1111,6 +1467,11 @@ func main() {
 			panic(err)
 		}
 
+		root.Arrange()
+
+		// Revealing a cursor arranges the text area again if it scrolls.
+		RevealCursors(root)
+
 		texture, err := root.Render(renderer)
 		if err != nil {
 			panic(err)
@@ -1121,12 +1482,21 @@ func main() {
 			panic(err)
 		}
 
-		renderer.Copy(texture, &sdl.Rect{0, 0, windowW, windowH}, &sdl.Rect{0, 0, windowW, windowH})
-
-		texture.Destroy()
+		renderer.Copy(texture, &sdl.Rect{0, 0, outputW, outputH}, &sdl.Rect{0, 0, outputW, outputH})
 
 		renderer.Present()
+	}
+
+	if !usageError {
+		state.RememberFile(filePath, editor)
+	}
+
+	err = state.Save()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
 
-		sdl.Delay(20)
+	if sessionOn {
+		saveSession()
 	}
 }
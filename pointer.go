@@ -0,0 +1,387 @@
+package main
+
+// dragThreshold is how far the pointer has to move with a button held before
+// the press becomes a drag.
+const dragThreshold = 4
+
+// Pointer turns the SDL mouse events into hover, button and drag events on
+// the elements under the pointer.
+type Pointer struct {
+	Root *Element
+
+	X int32
+	Y int32
+	Buttons [3]bool
+
+	// Captured receives every button and drag event until all buttons are
+	// released, wherever the pointer is.
+	Captured *Element
+
+	// pressed is the target of the last press, which becomes the drag source
+	// if the pointer moves far enough before the button is released.
+	pressed *Element
+	pressedButton int
+	pressedX int32
+	pressedY int32
+
+	Dragging bool
+
+	// scrolling is the element whose scrollbar thumb is being dragged, and
+	// scrollGrab where along the thumb it was grabbed.
+	scrolling *Element
+	scrollVertical bool
+	scrollGrab int32
+}
+
+func NewPointer(root *Element) *Pointer {
+	return &Pointer{
+		Root: root,
+	}
+}
+
+// Capture sends the pointer events to element until all buttons are released.
+func (pointer *Pointer) Capture(element *Element) {
+	pointer.Captured = element
+}
+
+// Press sends a button press to the element under the pointer and returns the
+// Selectable element it focuses, if any.
+func (pointer *Pointer) Press(button int, x, y int32, clicks int, mod uint16) *Element {
+	if button < 0 || button >= len(pointer.Buttons) {
+		return nil
+	}
+
+	target := pointer.update(x, y, mod)
+
+	pointer.Buttons[button] = true
+
+	if target == nil {
+		return nil
+	}
+
+	// Scrollbars are drawn over the children of the element they scroll, so
+	// they are found by looking up from the element under the pointer.
+	if button == 0 && pointer.Captured == nil {
+		for element := target; element != nil; element = element.Parent {
+			if pointer.pressScrollbar(element, x, y) {
+				return nil
+			}
+		}
+	}
+
+	for element := target; element != nil; element = element.Parent {
+		element.MouseButtonClicks[button] = true
+	}
+
+	if !pointer.Dragging {
+		pointer.pressed = target
+		pointer.pressedButton = button
+		pointer.pressedX, pointer.pressedY = x, y
+	}
+
+	if !target.Dispatch(pointer.buttonEvent(target, MouseButtonEventDown, button, clicks, mod)) {
+		return nil
+	}
+
+	for element := target; element != nil; element = element.Parent {
+		if element.Selectable {
+			return element
+		}
+	}
+
+	return nil
+}
+
+func (pointer *Pointer) Move(x, y int32, mod uint16) {
+	pointer.update(x, y, mod)
+
+	if pointer.scrolling != nil {
+		pointer.dragScrollbar()
+		return
+	}
+
+	if pointer.pressed == nil || !pointer.Buttons[pointer.pressedButton] {
+		return
+	}
+
+	if pointer.Dragging {
+		if source := pointer.dragSource(); source != nil {
+			source.Dispatch(pointer.dragEvent(source, MouseDragEventMove, mod))
+		}
+
+		return
+	}
+
+	dx, dy := x - pointer.pressedX, y - pointer.pressedY
+
+	if dx * dx + dy * dy < dragThreshold * dragThreshold || !pointer.Root.Contains(pointer.pressed) {
+		return
+	}
+
+	pointer.Dragging = true
+
+	if pointer.Captured == nil {
+		pointer.Captured = pointer.pressed
+	}
+
+	source := pointer.dragSource()
+	source.Dispatch(pointer.dragEvent(source, MouseDragEventStart, mod))
+}
+
+func (pointer *Pointer) Release(button int, x, y int32, clicks int, mod uint16) {
+	if button < 0 || button >= len(pointer.Buttons) {
+		return
+	}
+
+	target := pointer.update(x, y, mod)
+
+	pointer.Buttons[button] = false
+
+	if pointer.scrolling != nil && button == 0 {
+		pointer.scrolling.scrollbarDragged = false
+		pointer.scrolling.Invalidate()
+		pointer.scrolling = nil
+		return
+	}
+
+	if target != nil {
+		target.Dispatch(pointer.buttonEvent(target, MouseButtonEventUp, button, clicks, mod))
+	}
+
+	dragged := pointer.Dragging && button == pointer.pressedButton
+
+	if dragged {
+		pointer.Dragging = false
+
+		if source := pointer.dragSource(); source != nil {
+			source.Dispatch(pointer.dragEvent(source, MouseDragEventEnd, mod))
+		}
+	}
+
+	// The click goes to the innermost element the button was both pressed
+	// and released on, unless the press turned into a drag. Leaving an
+	// element clears its flags.
+	for element := target; element != nil; element = element.Parent {
+		if element.MouseButtonClicks[button] && !dragged {
+			element.Dispatch(pointer.buttonEvent(element, MouseButtonEventClick, button, clicks, mod))
+			break
+		}
+	}
+
+	for element := target; element != nil; element = element.Parent {
+		element.MouseButtonClicks[button] = false
+	}
+
+	if button == pointer.pressedButton {
+		pointer.pressed = nil
+	}
+
+	if pointer.Buttons == [3]bool{} {
+		pointer.Captured = nil
+	}
+}
+
+// pressScrollbar starts dragging the thumb of a scrollbar of element under
+// the pointer, or pages towards the pointer if it is on the track.
+func (pointer *Pointer) pressScrollbar(element *Element, x, y int32) bool {
+	elementX, elementY := element.Locate()
+	x, y = x - elementX, y - elementY
+
+	for _, vertical := range([]bool{true, false}) {
+		track, thumb, ok := element.scrollbar(vertical)
+		if !ok || !inBox(x, y, track.X, track.Y, track.W, track.H) {
+			continue
+		}
+
+		position, thumbStart, thumbEnd, page := x, thumb.X, thumb.X + thumb.W, element.LastRenderedWidth
+		if vertical {
+			position, thumbStart, thumbEnd, page = y, thumb.Y, thumb.Y + thumb.H, element.LastRenderedHeight
+		}
+
+		// A page keeps a line of the previous one in view.
+		page = max(page - element.scrollLineHeight(), element.scrollLineHeight())
+
+		switch {
+		case position < thumbStart:
+			page = -page
+		case position >= thumbEnd:
+		default:
+			pointer.scrolling = element
+			pointer.scrollVertical = vertical
+			pointer.scrollGrab = position - thumbStart
+
+			element.stopScrollAnimation()
+			element.scrollbarDragged = true
+			element.Invalidate()
+
+			return true
+		}
+
+		if vertical {
+			element.ScrollBy(0, page, smoothScrolling)
+		} else {
+			element.ScrollBy(page, 0, smoothScrolling)
+		}
+
+		return true
+	}
+
+	return false
+}
+
+// dragScrollbar scrolls the element whose thumb is held so that the thumb
+// follows the pointer.
+func (pointer *Pointer) dragScrollbar() {
+	element := pointer.scrolling
+
+	if !pointer.Root.Contains(element) {
+		pointer.scrolling = nil
+		return
+	}
+
+	elementX, elementY := element.Locate()
+	track, _, _ := element.scrollbar(pointer.scrollVertical)
+
+	if pointer.scrollVertical {
+		element.ScrollTo(element.ScrollPositionX, element.scrollForThumb(true, pointer.Y - elementY - track.Y - pointer.scrollGrab), false)
+	} else {
+		element.ScrollTo(element.scrollForThumb(false, pointer.X - elementX - track.X - pointer.scrollGrab), element.ScrollPositionY, false)
+	}
+}
+
+// dragSource is the element that receives the drag events, or nil if it has
+// left the tree.
+func (pointer *Pointer) dragSource() *Element {
+	source := pointer.Captured
+	if source == nil {
+		source = pointer.pressed
+	}
+
+	if source == nil || !pointer.Root.Contains(source) {
+		return nil
+	}
+
+	return source
+}
+
+// update moves the pointer, updating which elements are hovered, and returns
+// the element that the next button event goes to.
+func (pointer *Pointer) update(x, y int32, mod uint16) *Element {
+	pointer.X, pointer.Y = x, y
+
+	target := pointer.Root.hover(x, y, x, y, true, mod)
+
+	if pointer.Captured != nil {
+		if pointer.Root.Contains(pointer.Captured) {
+			return pointer.Captured
+		}
+
+		pointer.Captured = nil
+	}
+
+	return target
+}
+
+func (pointer *Pointer) buttonEvent(target *Element, kind, button, clicks int, mod uint16) *MouseButtonEvent {
+	targetX, targetY := target.Locate()
+
+	return &MouseButtonEvent{
+		Type: kind,
+		Button: button,
+		Clicks: clicks,
+
+		X: pointer.X,
+		Y: pointer.Y,
+		LocalX: pointer.X - targetX,
+		LocalY: pointer.Y - targetY,
+		Mod: mod,
+
+		Pointer: pointer,
+	}
+}
+
+func (pointer *Pointer) dragEvent(target *Element, kind int, mod uint16) *MouseDragEvent {
+	targetX, targetY := target.Locate()
+
+	return &MouseDragEvent{
+		Type: kind,
+		Button: pointer.pressedButton,
+
+		X: pointer.X,
+		Y: pointer.Y,
+		LocalX: pointer.X - targetX,
+		LocalY: pointer.Y - targetY,
+		StartX: pointer.pressedX,
+		StartY: pointer.pressedY,
+		Mod: mod,
+
+		Pointer: pointer,
+	}
+}
+
+// hover updates the hover state of e and its descendants, and returns the
+// topmost element under the pointer, or nil if it is not over e. x and y are
+// relative to e.
+func (e *Element) hover(x, y, windowX, windowY int32, overMe bool, mod uint16) *Element {
+	if overMe != e.MouseHovering {
+		e.MouseHovering = overMe
+
+		e.Dispatch(&MouseHoverEvent{
+			Hovering: overMe,
+
+			X: windowX,
+			Y: windowY,
+			LocalX: x,
+			LocalY: y,
+			Mod: mod,
+		})
+	}
+
+	var target *Element
+
+	if overMe {
+		target = e
+	} else {
+		e.MouseButtonClicks = [3]bool{}
+	}
+
+	top := e.popupAt(x, y)
+
+	for _, popup := range(append([]*Element(nil), e.Popups...)) {
+		overPopup := overMe && popup == top
+
+		if !overPopup && !popup.MouseHovering {
+			continue
+		}
+
+		if hit := popup.hover(x - popup.LastRenderedX, y - popup.LastRenderedY, windowX, windowY, overPopup, mod); hit != nil {
+			target = hit
+		}
+	}
+
+	// Of overlapping children the last one is drawn on top. Overlays such as
+	// cursors let the pointer through to what they cover.
+	var topChild *Element
+
+	if overMe && top == nil && !e.HasModal() {
+		for _, child := range(e.Children) {
+			if !child.Overlay && inBox(x, y, child.LastRenderedX, child.LastRenderedY, child.LastRenderedWidth, child.LastRenderedHeight) {
+				topChild = child
+			}
+		}
+	}
+
+	for _, child := range(e.Children) {
+		overChild := child == topChild
+
+		if !overChild && !child.MouseHovering {
+			continue
+		}
+
+		if hit := child.hover(x - child.LastRenderedX, y - child.LastRenderedY, windowX, windowY, overChild, mod); hit != nil {
+			target = hit
+		}
+	}
+
+	return target
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"sort"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+var modalBackdropColor sdl.Color
+
+// OpenPopup shows popup above the children of e, which is normally the root.
+// Popups are placed at PositionX and PositionY instead of being laid out with
+// the other children, kept inside e, stacked by ZIndex and hit-tested before
+// anything underneath them.
+func (e *Element) OpenPopup(popup *Element) {
+	popup.Remove()
+
+	popup.Parent = e
+	popup.LaidOut = false
+
+	e.Popups = append(e.Popups, popup)
+
+	e.Invalidate()
+}
+
+func (e *Element) ClosePopup(popup *Element) {
+	for i, other := range(e.Popups) {
+		if other != popup {
+			continue
+		}
+
+		e.Popups = append(e.Popups[:i], e.Popups[i + 1:]...)
+		popup.Parent = nil
+
+		popup.ReleaseTextures()
+
+		e.Invalidate()
+
+		return
+	}
+}
+
+func (e *Element) IsPopup() bool {
+	if e.Parent == nil {
+		return false
+	}
+
+	for _, popup := range(e.Parent.Popups) {
+		if popup == e {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasModal reports whether an open popup of e blocks input to everything
+// below it.
+func (e *Element) HasModal() bool {
+	for _, popup := range(e.Popups) {
+		if popup.Modal {
+			return true
+		}
+	}
+
+	return false
+}
+
+// TopModal returns the topmost modal popup of e, which takes keyboard input
+// away from the rest of the tree.
+func (e *Element) TopModal() *Element {
+	for i := len(e.Popups) - 1; i >= 0; i-- {
+		if e.Popups[i].Modal {
+			return e.Popups[i]
+		}
+	}
+
+	return nil
+}
+
+func (e *Element) arrangePopups() {
+	sort.SliceStable(e.Popups, func(i, j int) bool {
+		return e.Popups[i].ZIndex < e.Popups[j].ZIndex
+	})
+
+	width, height := e.LastRenderedWidth, e.LastRenderedHeight
+
+	for _, popup := range(e.Popups) {
+		popup.LaidOut = false
+
+		popupWidth, popupHeight := popup.Arrange()
+
+		if popupWidth > width || popupHeight > height {
+			popup.LaidOut = true
+			popup.LayoutWidth = min(popupWidth, width)
+			popup.LayoutHeight = min(popupHeight, height)
+
+			popupWidth, popupHeight = popup.Arrange()
+		}
+
+		x, y := popup.PositionX, popup.PositionY
+
+		if popup.Centered {
+			x, y = (width - popupWidth) / 2, (height - popupHeight) / 2
+		}
+
+		popup.LastRenderedX = min(max(x, 0), width - popupWidth)
+		popup.LastRenderedY = min(max(y, 0), height - popupHeight)
+	}
+}
+
+func (e *Element) renderPopups(renderer *sdl.Renderer) error {
+	for _, popup := range(e.Popups) {
+		texture, err := popup.Render(renderer)
+		if err != nil {
+			return err
+		}
+
+		popup.RenderingTexture = texture
+	}
+
+	return nil
+}
+
+func (e *Element) paintPopups(renderer *sdl.Renderer) error {
+	for _, popup := range(e.Popups) {
+		if popup.Modal {
+			renderer.SetDrawColor(modalBackdropColor.R, modalBackdropColor.G, modalBackdropColor.B, modalBackdropColor.A)
+			renderer.FillRect(&sdl.Rect{0, 0, e.LastRenderedWidth, e.LastRenderedHeight})
+		}
+
+		if popup.alpha() == 0 {
+			continue
+		}
+
+		popup.paintShadow(renderer)
+
+		err := setElementOpacity(popup.RenderingTexture, popup.alpha())
+		if err != nil {
+			return err
+		}
+
+		err = renderer.Copy(popup.RenderingTexture, nil, &sdl.Rect{popup.LastRenderedX, popup.LastRenderedY, popup.LastRenderedWidth, popup.LastRenderedHeight})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// popupAt returns the topmost popup of e under the point, if any.
+func (e *Element) popupAt(x, y int32) *Element {
+	for i := len(e.Popups) - 1; i >= 0; i-- {
+		popup := e.Popups[i]
+
+		if inBox(x, y, popup.LastRenderedX, popup.LastRenderedY, popup.LastRenderedWidth, popup.LastRenderedHeight) {
+			return popup
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"time"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+var (
+	scrollbarTrackColor sdl.Color
+	scrollbarThumbColor sdl.Color
+	scrollbarThumbActiveColor sdl.Color
+)
+
+const (
+	scrollbarWidth = 10
+	scrollbarMinThumb = 24
+
+	// defaultScrollLineHeight is used for a line by elements whose children
+	// give no better estimate.
+	defaultScrollLineHeight = 20
+
+	scrollAnimationInterval = 16 * time.Millisecond
+)
+
+var (
+	wheelScrollLines = 3
+	smoothScrolling = true
+)
+
+// scrollAnimation moves an element towards a scroll position over several
+// frames.
+type scrollAnimation struct {
+	timer *Timer
+
+	targetX int32
+	targetY int32
+
+	// x and y are where the animation last left the element, to notice when
+	// something else scrolls it.
+	x int32
+	y int32
+}
+
+// maxScroll returns how far e can scroll along each axis. A vertical
+// scrollbar covers the right edge of the viewport, so content can scroll
+// far enough to come out from under it.
+func (e *Element) maxScroll() (int32, int32) {
+	viewportWidth := e.LastRenderedWidth
+	maxY := max(e.LastRenderedChildHeight - e.LastRenderedHeight, 0)
+
+	if e.ScrollY && maxY > 0 {
+		viewportWidth -= scrollbarWidth
+	}
+
+	return max(e.LastRenderedChildWidth - viewportWidth, 0), maxY
+}
+
+// clampScrollPosition keeps the scroll position of e inside its content and
+// reports whether it moved.
+func (e *Element) clampScrollPosition() bool {
+	oldX, oldY := e.ScrollPositionX, e.ScrollPositionY
+
+	maxX, maxY := e.maxScroll()
+
+	e.ScrollPositionX = min(max(e.ScrollPositionX, 0), maxX)
+	e.ScrollPositionY = min(max(e.ScrollPositionY, 0), maxY)
+
+	return e.ScrollPositionX != oldX || e.ScrollPositionY != oldY
+}
+
+func (e *Element) ClampScroll() {
+	if e.clampScrollPosition() {
+		e.Invalidate()
+	}
+}
+
+// scrollTarget is where e is scrolling to, which is ahead of its scroll
+// position while an animation runs.
+func (e *Element) scrollTarget() (int32, int32) {
+	if e.scrollAnimation != nil {
+		return e.scrollAnimation.targetX, e.scrollAnimation.targetY
+	}
+
+	return e.ScrollPositionX, e.ScrollPositionY
+}
+
+// ScrollTo scrolls e to a position, clamped to its content. A smooth scroll
+// gets there over several frames.
+func (e *Element) ScrollTo(x, y int32, smooth bool) {
+	maxX, maxY := e.maxScroll()
+
+	if !e.ScrollX {
+		maxX = 0
+	}
+	if !e.ScrollY {
+		maxY = 0
+	}
+
+	x, y = min(max(x, 0), maxX), min(max(y, 0), maxY)
+
+	if !smooth {
+		e.stopScrollAnimation()
+
+		if e.ScrollPositionX != x || e.ScrollPositionY != y {
+			e.ScrollPositionX, e.ScrollPositionY = x, y
+			e.Invalidate()
+		}
+
+		return
+	}
+
+	if e.scrollAnimation == nil {
+		e.scrollAnimation = &scrollAnimation{
+			x: e.ScrollPositionX,
+			y: e.ScrollPositionY,
+		}
+
+		e.scrollAnimation.timer = AddTimer(scrollAnimationInterval, true, e.stepScrollAnimation)
+	}
+
+	e.scrollAnimation.targetX, e.scrollAnimation.targetY = x, y
+}
+
+// ScrollBy scrolls e relative to where it is scrolling to.
+func (e *Element) ScrollBy(dx, dy int32, smooth bool) {
+	x, y := e.scrollTarget()
+
+	e.ScrollTo(x + dx, y + dy, smooth)
+}
+
+func (e *Element) stopScrollAnimation() {
+	if e.scrollAnimation != nil {
+		e.scrollAnimation.timer.Stop()
+		e.scrollAnimation = nil
+	}
+}
+
+func (e *Element) stepScrollAnimation() {
+	animation := e.scrollAnimation
+
+	// Anything else scrolling the element, such as revealing the cursor,
+	// takes over from the animation.
+	if e.ScrollPositionX != animation.x || e.ScrollPositionY != animation.y {
+		e.stopScrollAnimation()
+		return
+	}
+
+	e.ScrollPositionX = scrollStep(e.ScrollPositionX, animation.targetX)
+	e.ScrollPositionY = scrollStep(e.ScrollPositionY, animation.targetY)
+
+	animation.x, animation.y = e.ScrollPositionX, e.ScrollPositionY
+
+	e.Invalidate()
+
+	if e.ScrollPositionX == animation.targetX && e.ScrollPositionY == animation.targetY {
+		e.stopScrollAnimation()
+	}
+}
+
+// scrollStep covers part of the distance to target each frame, so that the
+// scroll slows down as it arrives.
+func scrollStep(position, target int32) int32 {
+	step := (target - position) * 2 / 5
+
+	if step == 0 && position < target {
+		step = 1
+	} else if step == 0 && position > target {
+		step = -1
+	}
+
+	return position + step
+}
+
+// scrollLineHeight is how far a line of e scrolls, judged by its children.
+func (e *Element) scrollLineHeight() int32 {
+	if e.EstimatedChildHeight > 0 {
+		return e.EstimatedChildHeight
+	}
+
+	return defaultScrollLineHeight
+}
+
+// Scroll scrolls the innermost element under the mouse that can still scroll
+// in the direction of the wheel, and reports whether one did.
+func (e *Element) Scroll(mouseX, mouseY, scrollX, scrollY int32) bool {
+	if e.Content != nil {
+		return false
+	}
+
+	if !inBox(mouseX, mouseY, e.LastRenderedX, e.LastRenderedY, e.LastRenderedWidth, e.LastRenderedHeight) {
+		return false
+	}
+
+	if popup := e.popupAt(mouseX - e.LastRenderedX, mouseY - e.LastRenderedY); popup != nil {
+		return popup.Scroll(mouseX - e.LastRenderedX, mouseY - e.LastRenderedY, scrollX, scrollY)
+	}
+
+	if e.HasModal() {
+		return false
+	}
+
+	for _, child := range(e.Children) {
+		if child.Scroll(mouseX - e.LastRenderedX, mouseY - e.LastRenderedY, scrollX, scrollY) {
+			return true
+		}
+	}
+
+	if !e.ScrollX && !e.ScrollY {
+		return false
+	}
+
+	oldX, oldY := e.scrollTarget()
+
+	line := e.scrollLineHeight() * int32(wheelScrollLines)
+
+	e.ScrollBy(scrollX * line, -scrollY * line, smoothScrolling)
+
+	x, y := e.scrollTarget()
+
+	return x != oldX || y != oldY
+}
+
+// overflows reports whether e scrolls along an axis and has content to
+// scroll there.
+func (e *Element) overflows(vertical bool) bool {
+	maxX, maxY := e.maxScroll()
+
+	if vertical {
+		return e.ScrollY && maxY > 0
+	}
+
+	return e.ScrollX && maxX > 0
+}
+
+// scrollbar returns the track and thumb of the vertical or horizontal
+// scrollbar of e, relative to e. The scrollbars are drawn over the content
+// along the inside of the border, and only when there is content to scroll.
+func (e *Element) scrollbar(vertical bool) (sdl.Rect, sdl.Rect, bool) {
+	if !e.overflows(vertical) {
+		return sdl.Rect{}, sdl.Rect{}, false
+	}
+
+	border := e.BorderWidth
+	width, height := e.LastRenderedWidth, e.LastRenderedHeight
+
+	// Where both scrollbars show, they leave the corner free.
+	var corner int32
+	if e.overflows(!vertical) {
+		corner = scrollbarWidth
+	}
+
+	maxX, maxY := e.maxScroll()
+
+	if vertical {
+		track := sdl.Rect{width - border - scrollbarWidth, border, scrollbarWidth, height - 2 * border - corner}
+		length, offset := thumbSpan(track.H, height, e.LastRenderedChildHeight, e.ScrollPositionY, maxY)
+
+		return track, sdl.Rect{track.X, track.Y + offset, track.W, length}, true
+	}
+
+	track := sdl.Rect{border, height - border - scrollbarWidth, width - 2 * border - corner, scrollbarWidth}
+	length, offset := thumbSpan(track.W, width - corner, e.LastRenderedChildWidth, e.ScrollPositionX, maxX)
+
+	return track, sdl.Rect{track.X + offset, track.Y, length, track.H}, true
+}
+
+// thumbSpan returns the length and offset of a thumb in a track, in
+// proportion to how much of the content the viewport shows.
+func thumbSpan(track, viewport, content, position, maxPosition int32) (int32, int32) {
+	length := min(max(int32(int64(track) * int64(viewport) / int64(content)), scrollbarMinThumb), track)
+
+	return length, int32(int64(track - length) * int64(position) / int64(maxPosition))
+}
+
+// scrollForThumb returns the scroll position that puts the start of the
+// thumb at offset in the track.
+func (e *Element) scrollForThumb(vertical bool, offset int32) int32 {
+	track, thumb, ok := e.scrollbar(vertical)
+	if !ok {
+		return 0
+	}
+
+	maxX, maxY := e.maxScroll()
+
+	space, maxPosition := track.W - thumb.W, maxX
+	if vertical {
+		space, maxPosition = track.H - thumb.H, maxY
+	}
+
+	if space <= 0 {
+		return 0
+	}
+
+	return int32(int64(min(max(offset, 0), space)) * int64(maxPosition) / int64(space))
+}
+
+func (e *Element) paintScrollbars(renderer *sdl.Renderer) {
+	for _, vertical := range([]bool{true, false}) {
+		track, thumb, ok := e.scrollbar(vertical)
+		if !ok {
+			continue
+		}
+
+		renderer.SetDrawColor(scrollbarTrackColor.R, scrollbarTrackColor.G, scrollbarTrackColor.B, scrollbarTrackColor.A)
+		renderer.FillRect(&track)
+
+		color := scrollbarThumbColor
+		if e.scrollbarDragged {
+			color = scrollbarThumbActiveColor
+		}
+
+		thumb.X, thumb.Y, thumb.W, thumb.H = thumb.X + 2, thumb.Y + 2, thumb.W - 4, thumb.H - 4
+
+		renderer.SetDrawColor(color.R, color.G, color.B, color.A)
+		fillRoundedRect(renderer, &thumb, scrollbarWidth / 2)
+	}
+}
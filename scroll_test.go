@@ -0,0 +1,106 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestThumbSpan(t *testing.T) {
+	tests := []struct {
+		name string
+
+		track int32
+		viewport int32
+		content int32
+		position int32
+		maxPosition int32
+
+		length int32
+		offset int32
+	}{
+		{"top", 100, 100, 400, 0, 300, 25, 0},
+		{"middle", 100, 100, 400, 150, 300, 25, 37},
+		{"bottom", 100, 100, 400, 300, 300, 25, 75},
+		{"minimum thumb", 100, 100, 10000, 9900, 9900, scrollbarMinThumb, 100 - scrollbarMinThumb},
+		{"track shorter than the minimum thumb", 20, 20, 1000, 490, 980, 20, 0},
+		{"barely overflowing", 100, 100, 101, 1, 1, 99, 1},
+	}
+
+	for _, test := range(tests) {
+		length, offset := thumbSpan(test.track, test.viewport, test.content, test.position, test.maxPosition)
+
+		if length != test.length || offset != test.offset {
+			t.Errorf("%s: thumbSpan(%d, %d, %d, %d, %d) = %d, %d, want %d, %d", test.name, test.track, test.viewport, test.content, test.position, test.maxPosition, length, offset, test.length, test.offset)
+		}
+	}
+}
+
+// scrolledElement returns an element laid out as if it were 100 by 100 and
+// showed part of content of the given size.
+func scrolledElement(scrollX, scrollY bool, contentWidth, contentHeight int32) *Element {
+	return &Element{
+		Width: 100,
+		Height: 100,
+
+		ScrollX: scrollX,
+		ScrollY: scrollY,
+
+		LastRenderedWidth: 100,
+		LastRenderedHeight: 100,
+		LastRenderedChildWidth: contentWidth,
+		LastRenderedChildHeight: contentHeight,
+	}
+}
+
+func TestScrollForThumb(t *testing.T) {
+	tests := []struct {
+		name string
+
+		element *Element
+		vertical bool
+		offset int32
+
+		position int32
+	}{
+		// The thumb is 25 long, leaving 75 to move along for 300 of scrolling.
+		{"start", scrolledElement(false, true, 100, 400), true, 0, 0},
+		{"middle", scrolledElement(false, true, 100, 400), true, 37, 148},
+		{"end", scrolledElement(false, true, 100, 400), true, 75, 300},
+		{"before the start", scrolledElement(false, true, 100, 400), true, -10, 0},
+		{"past the end", scrolledElement(false, true, 100, 400), true, 200, 300},
+
+		// A horizontal track leaves the corner to the vertical scrollbar,
+		// and the content scrolls out from under that scrollbar.
+		{"horizontal end", scrolledElement(true, true, 400, 400), false, 90 - scrollbarMinThumb, 310},
+
+		{"no overflow", scrolledElement(false, true, 100, 100), true, 50, 0},
+		{"axis that does not scroll", scrolledElement(false, true, 400, 400), false, 50, 0},
+	}
+
+	for _, test := range(tests) {
+		position := test.element.scrollForThumb(test.vertical, test.offset)
+
+		if position != test.position {
+			t.Errorf("%s: scrollForThumb(%v, %d) = %d, want %d", test.name, test.vertical, test.offset, position, test.position)
+		}
+	}
+}
+
+func TestScrollbarLeavesRoomForContent(t *testing.T) {
+	area := scrolledElement(false, true, 100, 400)
+
+	row := &Element{
+		Width: 100,
+		WidthPercent: true,
+		Height: 20,
+	}
+
+	area.AppendChild(row)
+
+	if width, _ := row.DeclaredSize(); width != 100 - scrollbarWidth {
+		t.Errorf("a full width row beside a scrollbar is %d wide, want %d", width, 100 - scrollbarWidth)
+	}
+
+	if maxX, _ := scrolledElement(true, true, 100, 400).maxScroll(); maxX != scrollbarWidth {
+		t.Errorf("content as wide as the viewport scrolls %d from under the scrollbar, want %d", maxX, scrollbarWidth)
+	}
+}
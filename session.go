@@ -0,0 +1,266 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// sessionSaveDelay is how long after the last edit the session is written,
+// so that a crash loses no more than that.
+const sessionSaveDelay = 2 * time.Second
+
+// Session is what was open when the editor last ran with sessions turned on.
+// The editor edits one file at a time, so each run records the file it had
+// open, most recent first, and keeps the files other runs left unsaved
+// changes in.
+type Session struct {
+	Files []*SessionFile `json:"files"`
+}
+
+// SessionFile is a file of a session, where the editor was in it and, if it
+// had changes that were not saved, its contents.
+type SessionFile struct {
+	Path string `json:"path"`
+	Position *FilePosition `json:"position"`
+
+	// Unsaved is set when the contents differ from the file, which may not
+	// exist yet, and are kept in the session directory.
+	Unsaved bool `json:"unsaved"`
+
+	// FileModified and FileSize describe the file on disk when the session
+	// was saved, to notice if it has changed since. A file that did not exist
+	// has a zero FileModified.
+	FileModified time.Time `json:"fileModified"`
+	FileSize int64 `json:"fileSize"`
+}
+
+func sessionDir() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "session"), nil
+}
+
+// LoadSession reads the session saved by the last run, and returns nil if
+// there is none.
+func LoadSession() (*Session, error) {
+	dir, err := sessionDir()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "session.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	session := &Session{}
+
+	err = json.Unmarshal(data, session)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(session.Files) == 0 {
+		return nil, errors.New("the saved session names no file")
+	}
+
+	return session, nil
+}
+
+// File returns the file of the session at path, or nil if the session does
+// not have it.
+func (session *Session) File(path string) *SessionFile {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	for _, file := range(session.Files) {
+		if file.Path == path {
+			return file
+		}
+	}
+
+	return nil
+}
+
+// bufferPath returns where the unsaved contents of the file at path are kept.
+// It is named after the path so that every file has its own.
+func bufferPath(dir, path string) string {
+	sum := sha256.Sum256([]byte(path))
+
+	return filepath.Join(dir, "buffer-" + hex.EncodeToString(sum[:8]))
+}
+
+// Contents returns the text to open for the file: the unsaved contents the
+// session kept, or otherwise the file as it is now. Unsaved contents that
+// have gone missing are an error, never an empty text that saving would
+// write over the file.
+func (file *SessionFile) Contents() ([]byte, error) {
+	if !file.Unsaved {
+		data, err := os.ReadFile(file.Path)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+
+		return data, err
+	}
+
+	dir, err := sessionDir()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(bufferPath(dir, file.Path))
+	if err != nil {
+		return nil, fmt.Errorf("the unsaved changes to %s cannot be read: %v", file.Path, err)
+	}
+
+	return data, nil
+}
+
+// ReopenFiles starts another run of the editor for each of files, with the
+// flags of this run and --restore, as a run edits one file at a time.
+func ReopenFiles(files []*SessionFile) error {
+	if len(files) == 0 {
+		return nil
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	for _, file := range(files) {
+		args := append(append([]string(nil), os.Args[1:]...), "--restore", file.Path)
+
+		command := exec.Command(executable, args...)
+		command.Stdout = os.Stdout
+		command.Stderr = os.Stderr
+
+		err = command.Start()
+		if err != nil {
+			return err
+		}
+
+		// The run is left to itself; waiting in the background only releases
+		// its resources once it quits.
+		go command.Wait()
+	}
+
+	return nil
+}
+
+// FileChanged reports whether the file on disk is not the one the session
+// was saved with, so that its unsaved contents are out of date.
+func (file *SessionFile) FileChanged() bool {
+	info, err := os.Stat(file.Path)
+	if err != nil {
+		return !file.FileModified.IsZero()
+	}
+
+	return !info.ModTime().Equal(file.FileModified) || info.Size() != file.FileSize
+}
+
+// unsavedChanges reports whether text differs from the file at path. Any
+// text is a change to a file that does not exist yet.
+func unsavedChanges(path, text string) (bool, error) {
+	saved, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return text != "", nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return string(saved) != text, nil
+}
+
+// SaveSession records the file open in editor as the most recent of the
+// session, keeping its contents if they have not been saved. The session is
+// read again first, so that the unsaved changes other runs left in it are
+// kept.
+func SaveSession(path string, editor *Editor) error {
+	dir, err := sessionDir()
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	file := &SessionFile{
+		Path: path,
+	}
+
+	text := editor.Text()
+
+	file.Unsaved, err = unsavedChanges(path, text)
+	if err != nil {
+		return err
+	}
+
+	if info, err := os.Stat(path); err == nil {
+		file.FileModified = info.ModTime()
+		file.FileSize = info.Size()
+	}
+
+	buffer := bufferPath(dir, path)
+
+	if file.Unsaved {
+		err = os.WriteFile(buffer, []byte(text), 0600)
+	} else {
+		err = os.Remove(buffer)
+		if errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	file.Position = editorPosition(editor)
+
+	session := &Session{
+		Files: []*SessionFile{file},
+	}
+
+	if previous, err := LoadSession(); err == nil && previous != nil {
+		for _, other := range(previous.Files) {
+			if other.Path != path && other.Unsaved {
+				session.Files = append(session.Files, other)
+			}
+		}
+	}
+
+	data, err := json.MarshalIndent(session, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	temp := filepath.Join(dir, "session.json.tmp")
+
+	err = os.WriteFile(temp, data, 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(temp, filepath.Join(dir, "session.json"))
+}
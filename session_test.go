@@ -0,0 +1,128 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"github.com/veandco/go-sdl2/ttf"
+)
+
+func TestSaveSessionKeepsUnsavedFiles(t *testing.T) {
+	err := ttf.Init()
+	if err != nil {
+		t.Skip(err)
+	}
+
+	font, err := ttf.OpenFont(fontPath, fontSize)
+	if err != nil {
+		t.Skip(err)
+	}
+
+	defer font.Close()
+
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	files := t.TempDir()
+	changed := filepath.Join(files, "changed")
+	clean := filepath.Join(files, "clean")
+	untitled := filepath.Join(files, "untitled")
+
+	for _, path := range([]string{changed, clean}) {
+		err = os.WriteFile(path, []byte("saved"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	editors := []struct {
+		path string
+		text string
+	}{
+		{changed, "changed"},
+		{untitled, "new"},
+		{clean, "saved"},
+	}
+
+	for _, test := range(editors) {
+		editor := NewEditor(&Element{Width: 100, Height: 100}, font)
+		editor.SetText([]byte(test.text))
+
+		err = SaveSession(test.path, editor)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	session, err := LoadSession()
+	if err != nil || session == nil {
+		t.Fatalf("LoadSession() = %v, %v", session, err)
+	}
+
+	if session.Files[0].Path != clean {
+		t.Errorf("most recent file %s, want %s", session.Files[0].Path, clean)
+	}
+
+	tests := []struct {
+		path string
+		contents string
+	}{
+		{changed, "changed"},
+		{untitled, "new"},
+		{clean, "saved"},
+	}
+
+	for _, test := range(tests) {
+		file := session.File(test.path)
+		if file == nil {
+			t.Errorf("%s: not in the session", test.path)
+			continue
+		}
+
+		data, err := file.Contents()
+		if err != nil {
+			t.Errorf("%s: %v", test.path, err)
+		} else if string(data) != test.contents {
+			t.Errorf("%s: contents %q, want %q", test.path, data, test.contents)
+		}
+	}
+}
+
+func TestSessionKeepsCursors(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	path := filepath.Join(t.TempDir(), "file")
+
+	editor := testEditor(t, "one\ntwo\nthree")
+	editor.SetCursors([][2]int{{1, 0}, {2, 2}, {3, 1}})
+
+	err := SaveSession(path, editor)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := LoadSession()
+	if err != nil || session == nil {
+		t.Fatalf("LoadSession() = %v, %v", session, err)
+	}
+
+	restored := testEditor(t, "one\ntwo\nthree")
+	session.File(path).Position.Restore(restored)
+
+	want := [][2]int{{1, 0}, {2, 2}, {3, 1}}
+	cursors := restored.cursorPositions()
+
+	if len(cursors) != len(want) {
+		t.Fatalf("cursors %v, want %v", cursors, want)
+	}
+
+	for i := range(want) {
+		if cursors[i] != want[i] {
+			t.Errorf("cursors %v, want %v", cursors, want)
+			break
+		}
+	}
+
+	if primary := restored.PrimaryCursor(); primary.X != 3 || primary.Y != 1 {
+		t.Errorf("primary cursor at %d, %d, want 3, 1", primary.X, primary.Y)
+	}
+}
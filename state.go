@@ -0,0 +1,307 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// maxRememberedFiles is how many files keep their position in the state
+// file. The ones opened longest ago are forgotten first.
+const maxRememberedFiles = 200
+
+// minVisibleWindow is how much of a restored window has to be on a display,
+// in window coordinates, for it to be left where it was.
+const minVisibleWindow = 64
+
+// State is what is remembered between runs, kept as JSON in the user's
+// configuration directory.
+type State struct {
+	Window *WindowState `json:"window,omitempty"`
+
+	// Files maps absolute paths to where the editor was in them.
+	Files map[string]*FilePosition `json:"files,omitempty"`
+
+	// remembered holds the paths RememberFile was called with in this run,
+	// the only files Save writes over when another instance has saved since.
+	remembered map[string]bool
+}
+
+// WindowState is the geometry of the window in window coordinates. While the
+// window is maximized it keeps the geometry the window returns to.
+type WindowState struct {
+	X int32 `json:"x"`
+	Y int32 `json:"y"`
+	Width int32 `json:"width"`
+	Height int32 `json:"height"`
+	Maximized bool `json:"maximized"`
+}
+
+type FilePosition struct {
+	Line int `json:"line"`
+	Column int `json:"column"`
+	ScrollX int32 `json:"scrollX"`
+	ScrollY int32 `json:"scrollY"`
+
+	// Cursors holds the x and y position of every cursor, the primary one
+	// last, when there is more than one.
+	Cursors [][2]int `json:"cursors,omitempty"`
+
+	Opened time.Time `json:"opened"`
+}
+
+func stateDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "ashkmodify"), nil
+}
+
+func statePath() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "state.json"), nil
+}
+
+// LoadState reads the state saved by the last run. A missing state file
+// gives an empty state.
+func LoadState() (*State, error) {
+	state := &State{
+		Files: map[string]*FilePosition{},
+	}
+
+	path, err := statePath()
+	if err != nil {
+		return state, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	} else if err != nil {
+		return state, err
+	}
+
+	err = json.Unmarshal(data, state)
+	if state.Files == nil {
+		state.Files = map[string]*FilePosition{}
+	}
+
+	return state, err
+}
+
+// Save writes the state, replacing the state file only once the new one is
+// complete. Other instances may have saved while this one ran, so the state
+// file is read again and only the window and the files remembered in this
+// run are replaced in it.
+func (state *State) Save() error {
+	path, err := statePath()
+	if err != nil {
+		return err
+	}
+
+	saved, err := LoadState()
+	if err != nil {
+		// A state file that cannot be read is replaced rather than keeping
+		// this run from saving.
+		saved = &State{
+			Files: map[string]*FilePosition{},
+		}
+	}
+
+	if state.Window != nil {
+		saved.Window = state.Window
+	}
+
+	for file := range(state.remembered) {
+		saved.Files[file] = state.Files[file]
+	}
+
+	saved.remembered = state.remembered
+	*state = *saved
+
+	state.forgetOldFiles()
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(state, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	temp := path + ".tmp"
+
+	err = os.WriteFile(temp, data, 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(temp, path)
+}
+
+func (state *State) forgetOldFiles() {
+	if len(state.Files) <= maxRememberedFiles {
+		return
+	}
+
+	paths := make([]string, 0, len(state.Files))
+	for path := range(state.Files) {
+		paths = append(paths, path)
+	}
+
+	sort.Slice(paths, func(i, j int) bool {
+		return state.Files[paths[i]].Opened.After(state.Files[paths[j]].Opened)
+	})
+
+	for _, path := range(paths[maxRememberedFiles:]) {
+		delete(state.Files, path)
+	}
+}
+
+// FilePosition returns where the editor was in the file at path, or nil if
+// it is not remembered.
+func (state *State) FilePosition(path string) *FilePosition {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	return state.Files[path]
+}
+
+// RememberFile records where editor is in the file at path.
+func (state *State) RememberFile(path string, editor *Editor) {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	state.Files[path] = editorPosition(editor)
+
+	if state.remembered == nil {
+		state.remembered = map[string]bool{}
+	}
+
+	state.remembered[path] = true
+}
+
+// editorPosition returns where the cursors of editor are and how far the
+// text is scrolled.
+func editorPosition(editor *Editor) *FilePosition {
+	cursors := editor.cursorPositions()
+	primary := cursors[len(cursors) - 1]
+
+	position := &FilePosition{
+		Line: primary[1],
+		Column: primary[0],
+		ScrollX: editor.TextArea.ScrollPositionX,
+		ScrollY: editor.TextArea.ScrollPositionY,
+
+		Opened: time.Now(),
+	}
+
+	if len(cursors) > 1 {
+		position.Cursors = cursors
+	}
+
+	return position
+}
+
+// Restore puts the cursors and scroll position of editor back where position
+// says. The cursors are clamped to the text, which may have changed since,
+// and the primary one is then revealed in case the scroll position no longer
+// shows it.
+func (position *FilePosition) Restore(editor *Editor) {
+	if len(position.Cursors) > 0 {
+		editor.SetCursors(position.Cursors)
+	} else {
+		editor.SetCursor(position.Column, position.Line)
+	}
+
+	editor.TextArea.ScrollPositionX = position.ScrollX
+	editor.TextArea.ScrollPositionY = position.ScrollY
+
+	editor.revealPending = true
+	editor.TextArea.Invalidate()
+}
+
+// Track records the geometry of window, which is only taken while the window
+// is neither maximized nor minimized so that it can be restored to it.
+func (windowState *WindowState) Track(window *sdl.Window) {
+	flags := window.GetFlags()
+
+	if flags & sdl.WINDOW_MINIMIZED != 0 {
+		return
+	}
+
+	windowState.Maximized = flags & sdl.WINDOW_MAXIMIZED != 0
+
+	if flags & (sdl.WINDOW_MAXIMIZED | sdl.WINDOW_FULLSCREEN) != 0 {
+		return
+	}
+
+	windowState.X, windowState.Y = window.GetPosition()
+	windowState.Width, windowState.Height = window.GetSize()
+}
+
+// fitOnScreen moves the window back onto a display if the displays have
+// changed so that too little of it would show, and shrinks it to fit the
+// display it ends up on.
+func (windowState *WindowState) fitOnScreen() {
+	displays, err := sdl.GetNumVideoDisplays()
+	if err != nil || displays < 1 {
+		return
+	}
+
+	var best sdl.Rect
+	var bestArea int32 = -1
+
+	window := sdl.Rect{windowState.X, windowState.Y, windowState.Width, windowState.Height}
+
+	for i := 0; i < displays; i++ {
+		bounds, err := sdl.GetDisplayUsableBounds(i)
+		if err != nil {
+			continue
+		}
+
+		overlap, ok := window.Intersect(&bounds)
+
+		var area int32
+		if ok {
+			area = overlap.W * overlap.H
+		}
+
+		if area > bestArea {
+			best, bestArea = bounds, area
+		}
+
+		if ok && overlap.W >= min(minVisibleWindow, window.W) && overlap.H >= min(minVisibleWindow, window.H) {
+			// The title bar has to be on screen to move the window.
+			if windowState.Y >= bounds.Y {
+				windowState.Width = min(windowState.Width, bounds.W)
+				windowState.Height = min(windowState.Height, bounds.H)
+
+				return
+			}
+		}
+	}
+
+	if bestArea < 0 {
+		return
+	}
+
+	windowState.Width = min(windowState.Width, best.W)
+	windowState.Height = min(windowState.Height, best.H)
+	windowState.X = best.X + (best.W - windowState.Width) / 2
+	windowState.Y = best.Y + (best.H - windowState.Height) / 2
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestSaveKeepsOtherInstances(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	first, err := LoadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := LoadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Both instances knew about /shared when they started.
+	first.Files["/shared"] = &FilePosition{Line: 1}
+	second.Files["/shared"] = &FilePosition{Line: 1}
+
+	first.Window = &WindowState{Width: 100, Height: 100}
+	first.Files["/first"] = &FilePosition{Line: 10}
+	first.remembered = map[string]bool{"/first": true}
+
+	second.Window = &WindowState{Width: 200, Height: 200}
+	second.Files["/second"] = &FilePosition{Line: 20}
+	second.Files["/shared"] = &FilePosition{Line: 2}
+	second.remembered = map[string]bool{"/second": true, "/shared": true}
+
+	if err := second.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := first.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := LoadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		line int
+	}{
+		{"/first", 10},
+		{"/second", 20},
+		{"/shared", 2},
+	}
+
+	for _, test := range(tests) {
+		position := state.Files[test.path]
+
+		if position == nil {
+			t.Errorf("%s: not remembered", test.path)
+		} else if position.Line != test.line {
+			t.Errorf("%s: line %d, want %d", test.path, position.Line, test.line)
+		}
+	}
+
+	if state.Window == nil || state.Window.Width != 100 {
+		t.Errorf("window %+v, want the one of the instance that saved last", state.Window)
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"math"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// insets returns the space between each edge of the element and its content,
+// which is the border plus the padding on that side.
+func (e *Element) insets() (int32, int32, int32, int32) {
+	return e.BorderWidth + e.PaddingTop, e.BorderWidth + e.PaddingRight, e.BorderWidth + e.PaddingBottom, e.BorderWidth + e.PaddingLeft
+}
+
+// premultipliedBlendMode composites element textures. Anything drawn with
+// BLENDMODE_BLEND into a transparent texture comes out with its colors
+// already multiplied by its alpha, so blending that texture the usual way
+// would apply the alpha a second time and darken soft edges such as those of
+// glyphs.
+var premultipliedBlendMode = sdl.ComposeCustomBlendMode(
+	sdl.BLENDFACTOR_ONE, sdl.BLENDFACTOR_ONE_MINUS_SRC_ALPHA, sdl.BLENDOPERATION_ADD,
+	sdl.BLENDFACTOR_ONE, sdl.BLENDFACTOR_ONE_MINUS_SRC_ALPHA, sdl.BLENDOPERATION_ADD,
+)
+
+// premultipliedAlpha is whether element textures hold premultiplied colors.
+// It is cleared for renderers without custom blend modes, such as the
+// software renderer, which fall back to straight alpha.
+var premultipliedAlpha = true
+
+// setElementBlendMode sets up a new element texture for compositing.
+func setElementBlendMode(texture *sdl.Texture) error {
+	if premultipliedAlpha {
+		if texture.SetBlendMode(premultipliedBlendMode) == nil {
+			return nil
+		}
+
+		premultipliedAlpha = false
+	}
+
+	return texture.SetBlendMode(sdl.BLENDMODE_BLEND)
+}
+
+// setElementOpacity makes an element texture draw with the given alpha. The
+// colors of a premultiplied texture are scaled along with its alpha.
+func setElementOpacity(texture *sdl.Texture, alpha uint8) error {
+	if premultipliedAlpha {
+		err := texture.SetColorMod(alpha, alpha, alpha)
+		if err != nil {
+			return err
+		}
+	}
+
+	return texture.SetAlphaMod(alpha)
+}
+
+// premultiply returns color as an element texture stores it.
+func premultiply(color sdl.Color) sdl.Color {
+	if !premultipliedAlpha {
+		return color
+	}
+
+	scale := func(v uint8) uint8 {
+		return uint8(uint32(v) * uint32(color.A) / 255)
+	}
+
+	return sdl.Color{scale(color.R), scale(color.G), scale(color.B), color.A}
+}
+
+func (e *Element) alpha() uint8 {
+	if !e.OpacitySet {
+		return 255
+	}
+
+	return uint8(min(max(e.Opacity, 0), 1) * 255)
+}
+
+func (e *Element) SetOpacity(opacity float32) {
+	e.Opacity = opacity
+	e.OpacitySet = true
+
+	if e.Parent != nil {
+		e.Parent.Invalidate()
+	}
+}
+
+func (e *Element) rounded() bool {
+	return e.CornerRadius > 0
+}
+
+func fillRoundedRect(renderer *sdl.Renderer, rect *sdl.Rect, radius int32) {
+	radius = min(radius, rect.W / 2, rect.H / 2)
+
+	if radius <= 0 {
+		renderer.FillRect(rect)
+		return
+	}
+
+	for y := int32(0); y < radius; y++ {
+		dy := float64(radius - y) - 0.5
+		inset := radius - int32(math.Sqrt(float64(radius * radius) - dy * dy) + 0.5)
+
+		renderer.FillRect(&sdl.Rect{rect.X + inset, rect.Y + y, rect.W - 2 * inset, 1})
+		renderer.FillRect(&sdl.Rect{rect.X + inset, rect.Y + rect.H - 1 - y, rect.W - 2 * inset, 1})
+	}
+
+	renderer.FillRect(&sdl.Rect{rect.X, rect.Y + radius, rect.W, rect.H - 2 * radius})
+}
+
+// paintBox fills the background and border of an element the size of the
+// current render target.
+func (e *Element) paintBox(renderer *sdl.Renderer, width, height int32) error {
+	if !e.rounded() {
+		// Clearing replaces the pixels rather than blending into them, so
+		// the color is stored the way blending would have left it.
+		background := premultiply(e.BackgroundColor)
+
+		renderer.SetDrawColor(background.R, background.G, background.B, background.A)
+		err := renderer.Clear()
+		if err != nil {
+			return err
+		}
+
+		if e.BorderWidth > 0 {
+			renderer.SetDrawColor(e.BorderColor.R, e.BorderColor.G, e.BorderColor.B, e.BorderColor.A)
+			return drawThickRect(renderer, &sdl.Rect{0, 0, width, height}, e.BorderWidth)
+		}
+
+		return nil
+	}
+
+	renderer.SetDrawColor(0, 0, 0, 0)
+	err := renderer.Clear()
+	if err != nil {
+		return err
+	}
+
+	if e.BorderWidth > 0 {
+		renderer.SetDrawColor(e.BorderColor.R, e.BorderColor.G, e.BorderColor.B, e.BorderColor.A)
+		fillRoundedRect(renderer, &sdl.Rect{0, 0, width, height}, e.CornerRadius)
+	}
+
+	border := e.BorderWidth
+
+	renderer.SetDrawColor(e.BackgroundColor.R, e.BackgroundColor.G, e.BackgroundColor.B, e.BackgroundColor.A)
+	fillRoundedRect(renderer, &sdl.Rect{border, border, width - 2 * border, height - 2 * border}, max(e.CornerRadius - border, 0))
+
+	return nil
+}
+
+// paintShadow draws the drop shadow of e into its parent. A blurred shadow is
+// approximated by stacking translucent rounded rectangles of growing size.
+func (e *Element) paintShadow(renderer *sdl.Renderer) {
+	if e.ShadowColor.A == 0 {
+		return
+	}
+
+	layers := e.ShadowBlur + 1
+
+	renderer.SetDrawColor(e.ShadowColor.R, e.ShadowColor.G, e.ShadowColor.B, uint8(int32(e.ShadowColor.A) / layers))
+
+	for i := e.ShadowBlur; i >= 0; i-- {
+		fillRoundedRect(renderer, &sdl.Rect{
+			e.LastRenderedX + e.ShadowOffsetX - i,
+			e.LastRenderedY + e.ShadowOffsetY - i,
+			e.LastRenderedWidth + 2 * i,
+			e.LastRenderedHeight + 2 * i,
+		}, e.CornerRadius + i)
+	}
+}
@@ -0,0 +1,409 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// Theme names the colors of the interface. Elements take their colors from
+// package variables that SetTheme assigns, and restyle themselves when they
+// receive a ThemeEvent.
+type Theme struct {
+	Name string
+
+	TopBar sdl.Color
+	Editor sdl.Color
+	Text sdl.Color
+	Cursor sdl.Color
+	Selection sdl.Color
+	WrapIndicator sdl.Color
+	FocusRing sdl.Color
+	ModalBackdrop sdl.Color
+
+	Dialog sdl.Color
+	WidgetText sdl.Color
+	WidgetBorder sdl.Color
+	WidgetField sdl.Color
+	Button sdl.Color
+	ButtonHover sdl.Color
+	ButtonPressed sdl.Color
+	Accent sdl.Color
+
+	ScrollbarTrack sdl.Color
+	ScrollbarThumb sdl.Color
+	ScrollbarThumbActive sdl.Color
+
+	// Syntax maps token kinds, such as "keyword" or "comment", to colors for
+	// syntax highlighting.
+	Syntax map[string]sdl.Color
+}
+
+var (
+	topBarColor sdl.Color
+	editorColor sdl.Color
+	editorTextColor sdl.Color
+	wrapIndicatorColor sdl.Color
+)
+
+var darkTheme = &Theme{
+	Name: "dark",
+
+	TopBar: sdl.Color{32, 32, 32, 255},
+	Editor: sdl.Color{64, 64, 64, 255},
+	Text: sdl.Color{255, 255, 255, 255},
+	Cursor: sdl.Color{0, 255, 255, 255},
+	Selection: sdl.Color{38, 79, 120, 255},
+	WrapIndicator: sdl.Color{128, 128, 128, 255},
+	FocusRing: sdl.Color{0, 122, 204, 255},
+	ModalBackdrop: sdl.Color{0, 0, 0, 96},
+
+	Dialog: sdl.Color{48, 48, 48, 255},
+	WidgetText: sdl.Color{255, 255, 255, 255},
+	WidgetBorder: sdl.Color{80, 80, 80, 255},
+	WidgetField: sdl.Color{32, 32, 32, 255},
+	Button: sdl.Color{64, 64, 64, 255},
+	ButtonHover: sdl.Color{80, 80, 80, 255},
+	ButtonPressed: sdl.Color{40, 40, 40, 255},
+	Accent: sdl.Color{38, 79, 120, 255},
+
+	ScrollbarTrack: sdl.Color{255, 255, 255, 16},
+	ScrollbarThumb: sdl.Color{255, 255, 255, 96},
+	ScrollbarThumbActive: sdl.Color{255, 255, 255, 160},
+
+	Syntax: map[string]sdl.Color{
+		"keyword": {86, 156, 214, 255},
+		"string": {206, 145, 120, 255},
+		"comment": {106, 153, 85, 255},
+		"number": {181, 206, 168, 255},
+	},
+}
+
+var lightTheme = &Theme{
+	Name: "light",
+
+	TopBar: sdl.Color{221, 221, 221, 255},
+	Editor: sdl.Color{255, 255, 255, 255},
+	Text: sdl.Color{0, 0, 0, 255},
+	Cursor: sdl.Color{0, 0, 0, 255},
+	Selection: sdl.Color{173, 214, 255, 255},
+	WrapIndicator: sdl.Color{160, 160, 160, 255},
+	FocusRing: sdl.Color{0, 95, 184, 255},
+	ModalBackdrop: sdl.Color{0, 0, 0, 64},
+
+	Dialog: sdl.Color{243, 243, 243, 255},
+	WidgetText: sdl.Color{0, 0, 0, 255},
+	WidgetBorder: sdl.Color{160, 160, 160, 255},
+	WidgetField: sdl.Color{255, 255, 255, 255},
+	Button: sdl.Color{225, 225, 225, 255},
+	ButtonHover: sdl.Color{210, 210, 210, 255},
+	ButtonPressed: sdl.Color{190, 190, 190, 255},
+	Accent: sdl.Color{173, 214, 255, 255},
+
+	ScrollbarTrack: sdl.Color{0, 0, 0, 16},
+	ScrollbarThumb: sdl.Color{0, 0, 0, 80},
+	ScrollbarThumbActive: sdl.Color{0, 0, 0, 140},
+
+	Syntax: map[string]sdl.Color{
+		"keyword": {0, 0, 255, 255},
+		"string": {163, 21, 21, 255},
+		"comment": {0, 128, 0, 255},
+		"number": {9, 134, 88, 255},
+	},
+}
+
+var highContrastTheme = &Theme{
+	Name: "high-contrast",
+
+	TopBar: sdl.Color{0, 0, 0, 255},
+	Editor: sdl.Color{0, 0, 0, 255},
+	Text: sdl.Color{255, 255, 255, 255},
+	Cursor: sdl.Color{255, 255, 0, 255},
+	Selection: sdl.Color{0, 0, 200, 255},
+	WrapIndicator: sdl.Color{255, 255, 255, 255},
+	FocusRing: sdl.Color{255, 255, 0, 255},
+	ModalBackdrop: sdl.Color{0, 0, 0, 160},
+
+	Dialog: sdl.Color{0, 0, 0, 255},
+	WidgetText: sdl.Color{255, 255, 255, 255},
+	WidgetBorder: sdl.Color{255, 255, 255, 255},
+	WidgetField: sdl.Color{0, 0, 0, 255},
+	Button: sdl.Color{0, 0, 0, 255},
+	ButtonHover: sdl.Color{60, 60, 60, 255},
+	ButtonPressed: sdl.Color{100, 100, 100, 255},
+	Accent: sdl.Color{0, 0, 200, 255},
+
+	ScrollbarTrack: sdl.Color{255, 255, 255, 40},
+	ScrollbarThumb: sdl.Color{255, 255, 255, 200},
+	ScrollbarThumbActive: sdl.Color{255, 255, 0, 255},
+
+	Syntax: map[string]sdl.Color{
+		"keyword": {0, 255, 255, 255},
+		"string": {255, 160, 0, 255},
+		"comment": {0, 255, 0, 255},
+		"number": {255, 128, 255, 255},
+	},
+}
+
+var builtinThemes = []*Theme{darkTheme, lightTheme, highContrastTheme}
+
+// currentTheme is the theme last given to SetTheme.
+var currentTheme *Theme
+
+func init() {
+	darkTheme.apply()
+}
+
+// ThemeEvent is sent to every element in the tree when the theme changes.
+type ThemeEvent struct {
+	EventBase
+
+	Theme *Theme
+}
+
+// colors maps the names used in theme files to the colors of theme.
+func (theme *Theme) colors() map[string]*sdl.Color {
+	return map[string]*sdl.Color{
+		"top-bar": &theme.TopBar,
+		"editor": &theme.Editor,
+		"text": &theme.Text,
+		"cursor": &theme.Cursor,
+		"selection": &theme.Selection,
+		"wrap-indicator": &theme.WrapIndicator,
+		"focus-ring": &theme.FocusRing,
+		"modal-backdrop": &theme.ModalBackdrop,
+
+		"dialog": &theme.Dialog,
+		"widget-text": &theme.WidgetText,
+		"widget-border": &theme.WidgetBorder,
+		"widget-field": &theme.WidgetField,
+		"button": &theme.Button,
+		"button-hover": &theme.ButtonHover,
+		"button-pressed": &theme.ButtonPressed,
+		"accent": &theme.Accent,
+
+		"scrollbar-track": &theme.ScrollbarTrack,
+		"scrollbar-thumb": &theme.ScrollbarThumb,
+		"scrollbar-thumb-active": &theme.ScrollbarThumbActive,
+	}
+}
+
+func (theme *Theme) apply() {
+	currentTheme = theme
+
+	topBarColor = theme.TopBar
+	editorColor = theme.Editor
+	editorTextColor = theme.Text
+	cursorColor = theme.Cursor
+	blockHighlightColor = theme.Selection
+	wrapIndicatorColor = theme.WrapIndicator
+	focusRingColor = theme.FocusRing
+	modalBackdropColor = theme.ModalBackdrop
+
+	dialogColor = theme.Dialog
+	widgetTextColor = theme.WidgetText
+	widgetBorderColor = theme.WidgetBorder
+	widgetFieldColor = theme.WidgetField
+	widgetButtonColor = theme.Button
+	widgetButtonHoverColor = theme.ButtonHover
+	widgetButtonPressedColor = theme.ButtonPressed
+	widgetAccentColor = theme.Accent
+
+	scrollbarTrackColor = theme.ScrollbarTrack
+	scrollbarThumbColor = theme.ScrollbarThumb
+	scrollbarThumbActiveColor = theme.ScrollbarThumbActive
+}
+
+// SetTheme switches to theme and restyles the tree under root.
+func SetTheme(root *Element, theme *Theme) {
+	theme.apply()
+
+	root.dispatchTheme(theme)
+	root.Invalidate()
+
+	evictGlyphs(root)
+}
+
+func (e *Element) dispatchTheme(theme *Theme) {
+	e.Dispatch(&ThemeEvent{Theme: theme})
+
+	for _, child := range(append([]*Element(nil), e.Children...)) {
+		child.dispatchTheme(theme)
+	}
+
+	for _, popup := range(append([]*Element(nil), e.Popups...)) {
+		popup.dispatchTheme(theme)
+	}
+}
+
+// OnTheme calls style now and again whenever the theme changes while e is in
+// the tree, so that it can take its colors from the new theme.
+func (e *Element) OnTheme(style func()) {
+	style()
+
+	e.AddEventHandler(func(event Event) {
+		if _, ok := event.(*ThemeEvent); ok {
+			style()
+			e.Invalidate()
+		}
+	})
+}
+
+// NextTheme returns the theme after the current one among the built-in
+// themes and custom.
+func NextTheme(custom *Theme) *Theme {
+	themes := builtinThemes
+	if custom != nil {
+		themes = append(append([]*Theme(nil), builtinThemes...), custom)
+	}
+
+	for i, theme := range(themes) {
+		if theme == currentTheme {
+			return themes[(i + 1) % len(themes)]
+		}
+	}
+
+	return themes[0]
+}
+
+func builtinTheme(name string) *Theme {
+	for _, theme := range(builtinThemes) {
+		if theme.Name == name {
+			return theme
+		}
+	}
+
+	return nil
+}
+
+// themeFile is the format of a theme file. Colors are written as #rrggbb or
+// #rrggbbaa, and the ones left out are taken from the built-in theme named
+// by Base, dark by default.
+type themeFile struct {
+	Name string `json:"name"`
+	Base string `json:"base"`
+	Colors map[string]string `json:"colors"`
+	Syntax map[string]string `json:"syntax"`
+}
+
+func LoadTheme(path string) (*Theme, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file themeFile
+
+	err = json.Unmarshal(data, &file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	base := darkTheme
+	if file.Base != "" {
+		base = builtinTheme(file.Base)
+
+		if base == nil {
+			return nil, fmt.Errorf("%s: unknown base theme %q", path, file.Base)
+		}
+	}
+
+	theme := *base
+	theme.Name = file.Name
+	if theme.Name == "" {
+		theme.Name = path
+	}
+
+	colors := theme.colors()
+
+	for name, value := range(file.Colors) {
+		color, ok := colors[name]
+		if !ok {
+			return nil, fmt.Errorf("%s: unknown color %q", path, name)
+		}
+
+		*color, err = parseColor(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", path, name, err)
+		}
+	}
+
+	theme.Syntax = map[string]sdl.Color{}
+
+	for name, color := range(base.Syntax) {
+		theme.Syntax[name] = color
+	}
+
+	for name, value := range(file.Syntax) {
+		theme.Syntax[name], err = parseColor(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: syntax %s: %w", path, name, err)
+		}
+	}
+
+	return &theme, nil
+}
+
+func parseColor(value string) (sdl.Color, error) {
+	hex, ok := strings.CutPrefix(value, "#")
+	if !ok || (len(hex) != 6 && len(hex) != 8) {
+		return sdl.Color{}, fmt.Errorf("color %q is not #rrggbb or #rrggbbaa", value)
+	}
+
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+
+	rgba, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return sdl.Color{}, fmt.Errorf("color %q is not #rrggbb or #rrggbbaa", value)
+	}
+
+	return sdl.Color{uint8(rgba >> 24), uint8(rgba >> 16), uint8(rgba >> 8), uint8(rgba)}, nil
+}
+
+// systemTheme returns the built-in theme matching the dark mode preference of
+// the system. SDL 2 does not report it, so it is asked for the way each
+// platform exposes it, falling back to dark. As that runs other programs, it
+// is only done when --theme system asks for it.
+func systemTheme() *Theme {
+	switch runtime.GOOS {
+	case "darwin":
+		// The key only exists in dark mode.
+		output, err := exec.Command("defaults", "read", "-g", "AppleInterfaceStyle").Output()
+		if err != nil {
+			return lightTheme
+		}
+
+		if strings.Contains(string(output), "Dark") {
+			return darkTheme
+		}
+
+		return lightTheme
+	case "windows":
+		output, err := exec.Command("reg", "query", `HKCU\Software\Microsoft\Windows\CurrentVersion\Themes\Personalize`, "/v", "AppsUseLightTheme").Output()
+		if err == nil && strings.Contains(string(output), "0x1") {
+			return lightTheme
+		}
+	default:
+		if gtkTheme := os.Getenv("GTK_THEME"); gtkTheme != "" {
+			if strings.Contains(strings.ToLower(gtkTheme), "dark") {
+				return darkTheme
+			}
+
+			return lightTheme
+		}
+
+		output, err := exec.Command("gsettings", "get", "org.gnome.desktop.interface", "color-scheme").Output()
+		if err == nil && !strings.Contains(string(output), "prefer-dark") {
+			return lightTheme
+		}
+	}
+
+	return darkTheme
+}
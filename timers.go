@@ -0,0 +1,95 @@
+package main
+
+import (
+	"time"
+)
+
+type Timer struct {
+	Interval time.Duration
+	Deadline time.Time
+	Repeat bool
+
+	Callback func()
+
+	Stopped bool
+}
+
+var timers []*Timer
+
+func AddTimer(interval time.Duration, repeat bool, callback func()) *Timer {
+	timer := &Timer{
+		Interval: interval,
+		Deadline: time.Now().Add(interval),
+		Repeat: repeat,
+
+		Callback: callback,
+	}
+
+	timers = append(timers, timer)
+
+	return timer
+}
+
+func (timer *Timer) Reset() {
+	timer.Deadline = time.Now().Add(timer.Interval)
+	timer.Stopped = false
+
+	for _, other := range(timers) {
+		if other == timer {
+			return
+		}
+	}
+
+	timers = append(timers, timer)
+}
+
+func (timer *Timer) Stop() {
+	timer.Stopped = true
+}
+
+func runTimers(now time.Time) {
+	for _, timer := range(append([]*Timer{}, timers...)) {
+		if timer.Stopped || now.Before(timer.Deadline) {
+			continue
+		}
+
+		if timer.Repeat {
+			for !now.Before(timer.Deadline) {
+				timer.Deadline = timer.Deadline.Add(timer.Interval)
+			}
+		} else {
+			timer.Stopped = true
+		}
+
+		timer.Callback()
+	}
+
+	var remaining []*Timer
+
+	for _, timer := range(timers) {
+		if !timer.Stopped {
+			remaining = append(remaining, timer)
+		}
+	}
+
+	timers = remaining
+}
+
+func nextTimerDeadline() (time.Time, bool) {
+	var next time.Time
+
+	found := false
+
+	for _, timer := range(timers) {
+		if timer.Stopped {
+			continue
+		}
+
+		if !found || timer.Deadline.Before(next) {
+			next = timer.Deadline
+			found = true
+		}
+	}
+
+	return next, found
+}
@@ -0,0 +1,97 @@
+package main
+
+// maxUndoSteps is how many edits can be undone. The oldest are forgotten
+// first.
+const maxUndoSteps = 200
+
+// Kinds of edit. Consecutive edits of the same kind, other than commands,
+// are undone together, so that undoing typing takes back a run of characters
+// rather than one at a time.
+const (
+	EditNone = iota
+	EditTyping
+	EditDeleting
+	EditCommand
+)
+
+// undoState is what an editor looked like before an edit: its text, its
+// cursors with the primary one last, and its block.
+type undoState struct {
+	Text string
+
+	Cursors [][2]int
+	Block *Block
+}
+
+func (editor *Editor) snapshot() *undoState {
+	state := &undoState{
+		Text: editor.Text(),
+		Cursors: editor.cursorPositions(),
+	}
+
+	if editor.Block != nil {
+		block := *editor.Block
+		state.Block = &block
+	}
+
+	return state
+}
+
+func (editor *Editor) restoreUndoState(state *undoState) {
+	editor.SetText([]byte(state.Text))
+
+	if state.Block != nil {
+		editor.SetBlock(state.Block.AnchorX, state.Block.AnchorY, state.Block.X, state.Block.Y)
+	} else {
+		editor.SetCursors(state.Cursors)
+	}
+
+	editor.lastEdit = EditNone
+	editor.revealPending = true
+}
+
+// RecordEdit remembers the editor as it is before an edit of the given kind,
+// to be gone back to by Undo. It records nothing when the edit continues one
+// of the same kind.
+func (editor *Editor) RecordEdit(kind int) {
+	if kind != EditCommand && kind == editor.lastEdit {
+		return
+	}
+
+	editor.undoStack = append(editor.undoStack, editor.snapshot())
+
+	if len(editor.undoStack) > maxUndoSteps {
+		editor.undoStack = editor.undoStack[1:]
+	}
+
+	editor.redoStack = nil
+	editor.lastEdit = kind
+}
+
+// Undo takes back the last edit.
+func (editor *Editor) Undo() {
+	if len(editor.undoStack) == 0 {
+		return
+	}
+
+	state := editor.undoStack[len(editor.undoStack) - 1]
+	editor.undoStack = editor.undoStack[:len(editor.undoStack) - 1]
+
+	editor.redoStack = append(editor.redoStack, editor.snapshot())
+
+	editor.restoreUndoState(state)
+}
+
+// Redo makes the last edit that was undone again.
+func (editor *Editor) Redo() {
+	if len(editor.redoStack) == 0 {
+		return
+	}
+
+	state := editor.redoStack[len(editor.redoStack) - 1]
+	editor.redoStack = editor.redoStack[:len(editor.redoStack) - 1]
+
+	editor.undoStack = append(editor.undoStack, editor.snapshot())
+
+	editor.restoreUndoState(state)
+}
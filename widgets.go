@@ -0,0 +1,571 @@
+package main
+
+import (
+	"strings"
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/veandco/go-sdl2/ttf"
+)
+
+var (
+	widgetTextColor sdl.Color
+	widgetBorderColor sdl.Color
+	widgetFieldColor sdl.Color
+	widgetButtonColor sdl.Color
+	widgetButtonHoverColor sdl.Color
+	widgetButtonPressedColor sdl.Color
+	widgetAccentColor sdl.Color
+)
+
+type Label struct {
+	Element *Element
+
+	Text string
+
+	Font *ttf.Font
+	Color sdl.Color
+}
+
+func NewLabel(font *ttf.Font, text string) *Label {
+	label := &Label{
+		Element: &Element{
+			Width: -1,
+			Height: -1,
+		},
+
+		Text: text,
+		Font: font,
+	}
+
+	label.Element.OnTheme(func() {
+		label.Color = widgetTextColor
+		label.SetText(label.Text)
+	})
+
+	return label
+}
+
+func (label *Label) SetText(text string) {
+	label.Text = text
+
+	// Text with no glyphs cannot be rendered, so an empty label keeps only
+	// the height of a line.
+	if text == "" {
+		label.Element.MinHeight = fontHeight(label.Font)
+		label.Element.SetContent(nil)
+		return
+	}
+
+	label.Element.SetContent(&Text{
+		Content: text,
+		Font: label.Font,
+		Color: label.Color,
+	})
+}
+
+type Button struct {
+	Element *Element
+
+	OnClick func()
+
+	Color sdl.Color
+	HoverColor sdl.Color
+	PressedColor sdl.Color
+
+	Hovered bool
+	Pressed bool
+}
+
+func NewButton(font *ttf.Font, label string, onClick func()) *Button {
+	return newButton(&Text{
+		Content: label,
+		Font: font,
+		Color: widgetTextColor,
+	}, 4, 12, onClick)
+}
+
+func NewImageButton(image *Image, onClick func()) *Button {
+	return newButton(image, 2, 2, onClick)
+}
+
+func newButton(content ElementContent, paddingY, paddingX int32, onClick func()) *Button {
+	button := &Button{
+		Element: &Element{
+			Width: -1,
+			Height: -1,
+
+			PaddingTop: paddingY,
+			PaddingRight: paddingX,
+			PaddingBottom: paddingY,
+			PaddingLeft: paddingX,
+
+			BorderWidth: 1,
+			CornerRadius: 6,
+
+			ShadowColor: sdl.Color{0, 0, 0, 96},
+			ShadowOffsetY: 1,
+			ShadowBlur: 2,
+
+			// Tab reaches buttons, but clicking one leaves focus where it was.
+			Selectable: true,
+		},
+
+		OnClick: onClick,
+	}
+
+	button.Element.SetContent(content)
+
+	button.Element.AddEventHandler(button.handleEvent)
+
+	button.Element.OnTheme(func() {
+		button.Color = widgetButtonColor
+		button.HoverColor = widgetButtonHoverColor
+		button.PressedColor = widgetButtonPressedColor
+
+		button.Element.BorderColor = widgetBorderColor
+
+		if text, ok := button.Element.Content.(*Text); ok {
+			text.Color = widgetTextColor
+		}
+
+		button.updateColor()
+	})
+
+	return button
+}
+
+func (button *Button) updateColor() {
+	color := button.Color
+
+	if button.Pressed {
+		color = button.PressedColor
+	} else if button.Hovered {
+		color = button.HoverColor
+	}
+
+	if button.Element.BackgroundColor != color {
+		button.Element.BackgroundColor = color
+		button.Element.Invalidate()
+	}
+}
+
+func (button *Button) handleEvent(event Event) {
+	switch e := event.(type) {
+	case *MouseHoverEvent:
+		button.Hovered = e.Hovering
+
+		if !button.Hovered {
+			button.Pressed = false
+		}
+	case *MouseButtonEvent:
+		if e.Button != 0 {
+			return
+		}
+
+		switch e.Type {
+		case MouseButtonEventDown:
+			button.Pressed = true
+			e.PreventDefault()
+		case MouseButtonEventUp:
+			button.Pressed = false
+		case MouseButtonEventClick:
+			if button.OnClick != nil {
+				button.OnClick()
+			}
+		}
+	case *KeyEvent:
+		if !isActivateKey(e) {
+			return
+		}
+
+		// A focused button in a dialog is pressed instead of the default one.
+		e.StopPropagation()
+
+		if button.OnClick != nil {
+			button.OnClick()
+		}
+	}
+
+	button.updateColor()
+}
+
+// isActivateKey reports whether e presses the focused button or checkbox.
+func isActivateKey(e *KeyEvent) bool {
+	if e.Type != sdl.KEYDOWN {
+		return false
+	}
+
+	return e.Code == sdl.K_SPACE || e.Code == sdl.K_RETURN || e.Code == sdl.K_KP_ENTER
+}
+
+// TextField is a single line of editable text. It is driven by an Editor, so
+// it shares the cursor movement, deletion and clipboard handling of the main
+// text area.
+type TextField struct {
+	Element *Element
+	Editor *Editor
+
+	OnChange func(string)
+	OnSubmit func(string)
+}
+
+func NewTextField(font *ttf.Font, text string) *TextField {
+	field := &TextField{
+		Element: &Element{
+			Width: 200,
+			Height: -1,
+
+			PaddingTop: 2,
+			PaddingRight: 6,
+			PaddingBottom: 2,
+			PaddingLeft: 6,
+
+			BorderWidth: 1,
+
+			Selectable: true,
+			IsTextInput: true,
+		},
+	}
+
+	field.Element.OnTheme(func() {
+		field.Element.BackgroundColor = widgetFieldColor
+		field.Element.BorderColor = widgetBorderColor
+	})
+
+	field.Editor = NewEditor(field.Element, font)
+	field.Editor.SetSoftWrap(false, 0)
+	field.Editor.ScrollMarginColumns = 1
+
+	field.Element.Children[0].MinHeight = fontHeight(font)
+
+	field.Element.AddEventHandler(field.HandleEvent)
+
+	field.SetText(text)
+
+	return field
+}
+
+func (field *TextField) Text() string {
+	return string(lineText(field.Element.Children[0]))
+}
+
+func (field *TextField) SetText(text string) {
+	row := field.Element.Children[0]
+
+	for _, char := range(rowChars(row)) {
+		char.Remove()
+	}
+
+	insertText(field.Editor, row, 0, []byte(text))
+
+	field.Editor.SetCursor(len(text), 0)
+	field.Editor.revealPending = true
+}
+
+func (field *TextField) HandleEvent(event Event) {
+	before := field.Text()
+
+	switch e := event.(type) {
+	case *TextEvent:
+		if e.Char == '\n' || e.Char == '\r' {
+			return
+		}
+
+		field.Editor.HandleEvent(event)
+	case *KeyEvent:
+		if e.Type != sdl.KEYDOWN {
+			return
+		}
+
+		ctrl := e.Mod & sdl.KMOD_CTRL != 0
+
+		switch e.Code {
+		case sdl.K_RETURN, sdl.K_KP_ENTER:
+			if field.OnSubmit != nil {
+				field.OnSubmit(field.Text())
+			}
+		case sdl.K_HOME:
+			field.Editor.SetCursor(0, 0)
+		case sdl.K_END:
+			field.Editor.SetCursor(len(before), 0)
+		case sdl.K_v:
+			if ctrl {
+				field.paste()
+			}
+		case sdl.K_LEFT, sdl.K_RIGHT, sdl.K_BACKSPACE, sdl.K_DELETE, sdl.K_c, sdl.K_x:
+			// Everything else the editor binds works on whole lines, which a
+			// single line field has no use for.
+			field.Editor.HandleEvent(event)
+		}
+
+		field.Editor.revealPending = true
+	}
+
+	if after := field.Text(); after != before && field.OnChange != nil {
+		field.OnChange(after)
+	}
+}
+
+func (field *TextField) paste() {
+	text, err := sdl.GetClipboardText()
+	if err != nil {
+		return
+	}
+
+	text = strings.NewReplacer("\r", "", "\n", " ").Replace(text)
+
+	for _, c := range([]byte(text)) {
+		field.Editor.HandleEvent(&TextEvent{Char: c})
+	}
+}
+
+type Checkbox struct {
+	Element *Element
+	Box *Element
+	Label *Label
+
+	Checked bool
+
+	OnChange func(bool)
+}
+
+func NewCheckbox(font *ttf.Font, label string, checked bool) *Checkbox {
+	checkbox := &Checkbox{
+		Element: &Element{
+			Width: -1,
+			Height: -1,
+
+			Layout: LayoutFlex,
+			Align: AlignCenter,
+			Gap: 8,
+
+			Selectable: true,
+		},
+
+		Box: &Element{
+			Width: 18,
+			Height: 18,
+
+			BorderWidth: 1,
+			CornerRadius: 3,
+		},
+
+		Label: NewLabel(font, label),
+	}
+
+	checkbox.Element.AppendChild(checkbox.Box)
+	checkbox.Element.AppendChild(checkbox.Label.Element)
+
+	checkbox.Element.AddEventHandler(func(event Event) {
+		switch e := event.(type) {
+		case *MouseButtonEvent:
+			if e.Button != 0 {
+				return
+			}
+
+			if e.Type == MouseButtonEventDown {
+				e.PreventDefault()
+			} else if e.Type == MouseButtonEventClick {
+				checkbox.Toggle()
+			}
+		case *KeyEvent:
+			if isActivateKey(e) {
+				e.StopPropagation()
+				checkbox.Toggle()
+			}
+		}
+	})
+
+	checkbox.Checked = checked
+
+	checkbox.Element.OnTheme(func() {
+		checkbox.Box.BorderColor = widgetBorderColor
+		checkbox.SetChecked(checkbox.Checked)
+	})
+
+	return checkbox
+}
+
+func (checkbox *Checkbox) SetChecked(checked bool) {
+	checkbox.Checked = checked
+
+	if checked {
+		checkbox.Box.BackgroundColor = widgetAccentColor
+	} else {
+		checkbox.Box.BackgroundColor = widgetFieldColor
+	}
+
+	checkbox.Box.Invalidate()
+}
+
+func (checkbox *Checkbox) Toggle() {
+	checkbox.SetChecked(!checkbox.Checked)
+
+	if checkbox.OnChange != nil {
+		checkbox.OnChange(checkbox.Checked)
+	}
+}
+
+// ListView is a scrollable column of items with one selected item, which is
+// moved with the arrow, Home, End and Page keys and activated with Enter.
+type ListView struct {
+	Element *Element
+
+	Items []string
+	Selected int
+
+	OnSelect func(int)
+	OnActivate func(int)
+
+	Font *ttf.Font
+}
+
+func NewListView(font *ttf.Font, items []string) *ListView {
+	list := &ListView{
+		Element: &Element{
+			Width: 200,
+			Height: 200,
+
+			ScrollY: true,
+
+			BorderWidth: 1,
+
+			Selectable: true,
+		},
+
+		Font: font,
+	}
+
+	list.Element.AddEventHandler(list.handleEvent)
+
+	list.Element.OnTheme(func() {
+		list.Element.BackgroundColor = widgetFieldColor
+		list.Element.BorderColor = widgetBorderColor
+
+		if list.Selected >= 0 && list.Selected < len(list.Element.Children) {
+			list.Element.Children[list.Selected].BackgroundColor = widgetAccentColor
+		}
+	})
+
+	list.SetItems(items)
+
+	return list
+}
+
+func (list *ListView) SetItems(items []string) {
+	list.Items = items
+
+	for len(list.Element.Children) > 0 {
+		list.Element.Children[0].Remove()
+	}
+
+	for i, item := range(items) {
+		label := NewLabel(list.Font, item)
+
+		label.Element.Width = 100
+		label.Element.WidthPercent = true
+		label.Element.Breaking = true
+
+		label.Element.PaddingTop = 2
+		label.Element.PaddingBottom = 2
+		label.Element.PaddingLeft = 6
+
+		label.Element.AddEventHandler(func(event Event) {
+			if e, ok := event.(*MouseButtonEvent); ok && e.Type == MouseButtonEventDown && e.Button == 0 {
+				list.Select(i)
+			}
+		})
+
+		list.Element.AppendChild(label.Element)
+	}
+
+	list.Selected = -1
+
+	if len(items) > 0 {
+		list.Select(0)
+	}
+}
+
+func (list *ListView) Select(i int) {
+	if len(list.Items) == 0 {
+		return
+	}
+
+	i = min(max(i, 0), len(list.Items) - 1)
+
+	if i == list.Selected {
+		return
+	}
+
+	if list.Selected >= 0 {
+		previous := list.Element.Children[list.Selected]
+		previous.BackgroundColor = sdl.Color{}
+		previous.Invalidate()
+	}
+
+	list.Selected = i
+
+	item := list.Element.Children[i]
+	item.BackgroundColor = widgetAccentColor
+	item.Invalidate()
+
+	list.reveal(item)
+
+	if list.OnSelect != nil {
+		list.OnSelect(i)
+	}
+}
+
+func (list *ListView) reveal(item *Element) {
+	area := list.Element
+
+	top, _, bottom, _ := area.insets()
+	viewport := area.LastRenderedHeight - top - bottom
+
+	y := item.LastRenderedY - top + area.ScrollPositionY
+
+	if y < area.ScrollPositionY {
+		area.ScrollPositionY = y
+	} else if y + item.LastRenderedHeight > area.ScrollPositionY + viewport {
+		area.ScrollPositionY = y + item.LastRenderedHeight - viewport
+	}
+
+	area.ClampScroll()
+	area.Invalidate()
+}
+
+// pageSize is how many items fit in the list, judged by the selected one.
+func (list *ListView) pageSize() int {
+	item := list.Element.Children[list.Selected]
+
+	if item.LastRenderedHeight == 0 {
+		return 1
+	}
+
+	return max(int(list.Element.LastRenderedHeight / item.LastRenderedHeight) - 1, 1)
+}
+
+func (list *ListView) handleEvent(event Event) {
+	e, ok := event.(*KeyEvent)
+	if !ok || e.Type != sdl.KEYDOWN || len(list.Items) == 0 {
+		return
+	}
+
+	switch e.Code {
+	case sdl.K_UP:
+		list.Select(list.Selected - 1)
+	case sdl.K_DOWN:
+		list.Select(list.Selected + 1)
+	case sdl.K_HOME:
+		list.Select(0)
+	case sdl.K_END:
+		list.Select(len(list.Items) - 1)
+	case sdl.K_PAGEUP:
+		list.Select(list.Selected - list.pageSize())
+	case sdl.K_PAGEDOWN:
+		list.Select(list.Selected + list.pageSize())
+	case sdl.K_RETURN, sdl.K_KP_ENTER:
+		if list.OnActivate != nil {
+			list.OnActivate(list.Selected)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+func (editor *Editor) styleLine(line *Element) {
+	line.WrapIndicator = editor.SoftWrap
+
+	// Cursors are drawn into their row, so the row keeps room for one after
+	// its last character.
+	cursorWidth, _ := editor.cursorSize()
+	line.PaddingRight = cursorWidth
+
+	if !editor.SoftWrap {
+		line.Width = -1
+		line.WidthPercent = false
+		line.MinWidth = 1
+		line.NoWrap = true
+	} else if editor.WrapColumn > 0 {
+		line.Width = int32(editor.WrapColumn) * charWidth(editor.Font) + cursorWidth
+		line.WidthPercent = false
+		line.NoWrap = false
+	} else {
+		line.Width = 100
+		line.WidthPercent = true
+		line.NoWrap = false
+	}
+
+	line.Invalidate()
+}
+
+func (editor *Editor) SetSoftWrap(softWrap bool, wrapColumn int) {
+	editor.SoftWrap = softWrap
+	editor.WrapColumn = wrapColumn
+
+	editor.TextArea.ScrollX = !softWrap
+	editor.TextArea.Invalidate()
+
+	for _, row := range(editor.TextArea.Children) {
+		editor.styleLine(row)
+	}
+}
+
+func visualLineStarts(chars []*Element) []int {
+	starts := []int{0}
+
+	for i := 1; i < len(chars); i++ {
+		if chars[i].LastRenderedY != chars[i - 1].LastRenderedY {
+			starts = append(starts, i)
+		}
+	}
+
+	return starts
+}
+
+func (editor *Editor) moveCursorVisually(cursor *Cursor, offset int) (int, int) {
+	rows := editor.TextArea.Children
+
+	chars := rowChars(rows[cursor.Y])
+	starts := visualLineStarts(chars)
+
+	line := 0
+	var targetX int32
+
+	if cursor.X > 0 {
+		previous := chars[cursor.X - 1]
+		targetX = previous.LastRenderedX + previous.LastRenderedWidth
+
+		for i, start := range(starts) {
+			if start <= cursor.X - 1 {
+				line = i
+			}
+		}
+	}
+
+	y := cursor.Y
+	line += offset
+
+	if line < 0 {
+		if y == 0 {
+			return cursor.X, y
+		}
+
+		y--
+		chars = rowChars(rows[y])
+		starts = visualLineStarts(chars)
+		line = len(starts) - 1
+	} else if line >= len(starts) {
+		if y + 1 >= len(rows) {
+			return cursor.X, y
+		}
+
+		y++
+		chars = rowChars(rows[y])
+		starts = visualLineStarts(chars)
+		line = 0
+	}
+
+	end := len(chars)
+	if line + 1 < len(starts) {
+		end = starts[line + 1]
+	}
+
+	x := starts[line]
+
+	for x < end && chars[x].LastRenderedX + chars[x].LastRenderedWidth / 2 < targetX {
+		x++
+	}
+
+	return x, y
+}